@@ -0,0 +1,335 @@
+package router
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultMaxMemory = 32 << 20 // 32 MB, matches net/http
+
+var ErrUnsupportedMediaType = NewHTTPError(http.StatusUnsupportedMediaType, "unsupported_media_type", "unsupported content type")
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// FieldError describes a single field that could not be bound or validated.
+type FieldError struct {
+	Field   string `json:"field"`
+	Source  string `json:"source,omitempty"`
+	Message string `json:"message"`
+	Err     error  `json:"-"`
+}
+
+func (e *FieldError) Error() string {
+
+	if e.Source != "" {
+		return e.Source + " " + e.Field + ": " + e.Message
+	}
+
+	return e.Field + ": " + e.Message
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Bind fills the struct pointed to by dst from the request. The body is
+// decoded according to its Content-Type: JSON bodies are unmarshalled into
+// dst, URL-encoded and multipart forms fill fields tagged `form:"name"`.
+// Fields tagged `path`, `query`, `header` and `cookie` are then filled from
+// the route params, query string, headers and cookies.
+//
+// Values are converted to strings, bools, numbers, time.Duration, slices,
+// pointers and any type implementing encoding.TextUnmarshaler. time.Time
+// fields accept RFC 3339 unless a `layout` tag is given. Conversion
+// failures are returned together as FieldErrors.
+func Bind(r *http.Request, dst interface{}) error {
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return errors.New("bind destination must be a non-nil pointer")
+	}
+
+	form, err := bindBody(r, dst)
+	if err != nil {
+		return err
+	}
+
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	b := binder{
+		req:   r,
+		query: r.URL.Query(),
+		form:  form,
+	}
+
+	b.bindStruct(v)
+
+	if len(b.errs) > 0 {
+		return b.errs
+	}
+
+	return nil
+}
+
+func bindBody(r *http.Request, dst interface{}) (*multipart.Form, error) {
+
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil, nil
+	}
+
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		ct = "application/json"
+	}
+
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return nil, ErrUnsupportedMediaType.Wrap(err)
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		err := json.NewDecoder(r.Body).Decode(dst)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, bodyError(err, "request body is not valid JSON")
+		}
+
+		return nil, nil
+
+	case mediaType == "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return nil, bodyError(err, "request body is not a valid form")
+		}
+
+		return &multipart.Form{Value: r.PostForm}, nil
+
+	case mediaType == "multipart/form-data":
+		if err := r.ParseMultipartForm(defaultMaxMemory); err != nil {
+			return nil, bodyError(err, "request body is not a valid multipart form")
+		}
+
+		return r.MultipartForm, nil
+	}
+
+	return nil, ErrUnsupportedMediaType
+}
+
+// bodyError reports a failure to read the body as 413 if a body limit was
+// exceeded and as 400 otherwise.
+func bodyError(err error, message string) error {
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return NewHTTPError(http.StatusRequestEntityTooLarge, "request_too_large", "request body too large").Wrap(err)
+	}
+
+	return NewHTTPError(http.StatusBadRequest, "invalid_body", message).Wrap(err)
+}
+
+type binder struct {
+	req   *http.Request
+	query url.Values
+	form  *multipart.Form
+	errs  FieldErrors
+}
+
+func (b *binder) bindStruct(v reflect.Value) {
+
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+
+		f := t.Field(i)
+		fv := v.Field(i)
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			b.bindStruct(fv)
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name, ok := f.Tag.Lookup("form"); ok && b.form != nil {
+			if f.Type == fileHeaderType || f.Type == reflect.SliceOf(fileHeaderType) {
+				b.bindFiles(fv, name)
+			} else if values, ok := b.form.Value[name]; ok {
+				b.bindField(f, fv, "form", name, values)
+			}
+		}
+
+		if name, ok := f.Tag.Lookup("path"); ok {
+			if s := RouteParam(b.req, name); s != "" {
+				b.bindField(f, fv, "path", name, []string{s})
+			}
+		}
+
+		if name, ok := f.Tag.Lookup("query"); ok {
+			if values, ok := b.query[name]; ok {
+				b.bindField(f, fv, "query", name, values)
+			}
+		}
+
+		if name, ok := f.Tag.Lookup("header"); ok {
+			if values := b.req.Header.Values(name); len(values) > 0 {
+				b.bindField(f, fv, "header", name, values)
+			}
+		}
+
+		if name, ok := f.Tag.Lookup("cookie"); ok {
+			if c, err := b.req.Cookie(name); err == nil {
+				b.bindField(f, fv, "cookie", name, []string{c.Value})
+			}
+		}
+	}
+}
+
+func (b *binder) bindFiles(v reflect.Value, name string) {
+
+	files := b.form.File[name]
+	if len(files) == 0 {
+		return
+	}
+
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.ValueOf(files))
+		return
+	}
+
+	v.Set(reflect.ValueOf(files[0]))
+}
+
+func (b *binder) bindField(f reflect.StructField, v reflect.Value, source, name string, values []string) {
+
+	if err := setValues(v, values, f.Tag.Get("layout")); err != nil {
+		b.errs = append(b.errs, &FieldError{
+			Field:   name,
+			Source:  source,
+			Message: err.Error(),
+			Err:     err,
+		})
+	}
+}
+
+func setValues(v reflect.Value, values []string, layout string) error {
+
+	if v.Kind() == reflect.Slice && !isScalar(v) {
+
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+
+		for i, value := range values {
+			if err := setValue(s.Index(i), value, layout); err != nil {
+				return err
+			}
+		}
+
+		v.Set(s)
+		return nil
+	}
+
+	return setValue(v, values[0], layout)
+}
+
+// isScalar reports whether v is set from a single string even though its
+// kind might suggest otherwise, such as net.IP.
+func isScalar(v reflect.Value) bool {
+	return reflect.PointerTo(v.Type()).Implements(textUnmarshalerType)
+}
+
+func setValue(v reflect.Value, s string, layout string) error {
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return setValue(v.Elem(), s, layout)
+	}
+
+	if v.Type() == timeType && layout != "" {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return fmt.Errorf("invalid time %q, expected layout %s", s, layout)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package router
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindTestRequest struct {
+	ID      int           `path:"id"`
+	Page    *int          `query:"page"`
+	Tags    []string      `query:"tag"`
+	Since   time.Time     `query:"since" layout:"2006-01-02"`
+	Timeout time.Duration `query:"timeout"`
+	Tenant  string        `header:"X-Tenant"`
+	Session string        `cookie:"sid"`
+	IP      net.IP        `header:"X-Client-IP"`
+	Name    string        `json:"name" form:"name"`
+}
+
+func TestBind(t *testing.T) {
+
+	var dst bindTestRequest
+
+	r := New()
+
+	r.Post("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		if err := Bind(r, &dst); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	req, _ := http.NewRequest("POST", "/users/5?page=2&tag=a&tag=b&since=2024-01-31&timeout=5s", strings.NewReader(`{"name":"ada"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Set("X-Client-IP", "10.0.0.1")
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "abc"})
+
+	r.ServeHTTP(nil, req)
+
+	if dst.ID != 5 || dst.Page == nil || *dst.Page != 2 {
+		t.Error("path or query param not bound")
+	}
+
+	if len(dst.Tags) != 2 || dst.Tags[1] != "b" {
+		t.Error("slice not bound, got ", dst.Tags)
+	}
+
+	if !dst.Since.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)) || dst.Timeout != 5*time.Second {
+		t.Error("time values not bound")
+	}
+
+	if dst.Tenant != "acme" || dst.Session != "abc" || dst.IP.String() != "10.0.0.1" {
+		t.Error("header or cookie not bound")
+	}
+
+	if dst.Name != "ada" {
+		t.Error("json body not bound")
+	}
+}
+
+func TestBind_Form(t *testing.T) {
+
+	var dst bindTestRequest
+
+	form := url.Values{"name": {"grace"}}
+	req, _ := http.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if err := Bind(req, &dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if dst.Name != "grace" {
+		t.Error("form value not bound, got ", dst.Name)
+	}
+}
+
+func TestBind_Multipart(t *testing.T) {
+
+	var dst struct {
+		Name string                `form:"name"`
+		File *multipart.FileHeader `form:"file"`
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("name", "linus")
+	fw, _ := mw.CreateFormFile("file", "notes.txt")
+	_, _ = fw.Write([]byte("hello"))
+	_ = mw.Close()
+
+	req, _ := http.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	if err := Bind(req, &dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if dst.Name != "linus" {
+		t.Error("multipart value not bound")
+	}
+
+	if dst.File == nil || dst.File.Filename != "notes.txt" {
+		t.Error("multipart file not bound")
+	}
+}
+
+func TestBind_FieldErrors(t *testing.T) {
+
+	var dst bindTestRequest
+
+	req, _ := http.NewRequest("GET", "/?page=one&timeout=soon", nil)
+
+	err := Bind(req, &dst)
+
+	var errs FieldErrors
+	if !errors.As(err, &errs) {
+		t.Fatal("expected FieldErrors, got ", err)
+	}
+
+	if len(errs) != 2 || errs[0].Field != "page" || errs[0].Source != "query" {
+		t.Error("unexpected field errors: ", errs)
+	}
+}
+
+func TestBind_UnsupportedMediaType(t *testing.T) {
+
+	var dst bindTestRequest
+
+	req, _ := http.NewRequest("POST", "/", strings.NewReader("<user/>"))
+	req.Header.Set("Content-Type", "application/xml")
+
+	if err := Bind(req, &dst); !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Error("expected ErrUnsupportedMediaType, got ", err)
+	}
+}
@@ -7,6 +7,9 @@ type Option func(*Config)
 type Config struct {
 	NotFoundHandler         http.HandlerFunc
 	MethodNotAllowedHandler http.HandlerFunc
+	ErrorHandler            ErrorHandler
+	ProblemDetails          bool
+	Policy                  *Policy
 }
 
 func WithNotFoundHandler(handler http.HandlerFunc) Option {
@@ -20,3 +23,26 @@ func WithMethodNotAllowedHandler(handler http.HandlerFunc) Option {
 		c.MethodNotAllowedHandler = handler
 	}
 }
+
+func WithErrorHandler(handler ErrorHandler) Option {
+	return func(c *Config) {
+		c.ErrorHandler = handler
+	}
+}
+
+// WithProblemDetails renders not found and method not allowed responses as
+// RFC 9457 problem details, and uses ProblemErrorHandler unless an
+// ErrorHandler is configured.
+func WithProblemDetails() Option {
+	return func(c *Config) {
+		c.ProblemDetails = true
+	}
+}
+
+// WithPolicy sets the policy that evaluates the requirements of routes
+// declared with Route.Require. The default is NewPolicy().
+func WithPolicy(policy *Policy) Option {
+	return func(c *Config) {
+		c.Policy = policy
+	}
+}
@@ -4,5 +4,6 @@ type contextKey string
 
 var (
 	contextKeyRoute = contextKey("route")
-	contextKeyLocal = contextKey("local")
+	contextKeyMount = contextKey("mount")
+	contextKeyError = contextKey("error")
 )
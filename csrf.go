@@ -0,0 +1,18 @@
+package router
+
+import "net/http"
+
+var csrfTokenKey = NewKey[string]("csrf_token")
+
+// CSRFToken returns the token set by middleware.CSRF for embedding in forms
+// and pages. It is masked differently for every request, so it is safe to
+// render into compressed responses.
+func CSRFToken(r *http.Request) string {
+	token, _ := csrfTokenKey.Get(r)
+	return token
+}
+
+// SetCSRFToken returns a copy of r with the given CSRF token.
+func SetCSRFToken(r *http.Request, token string) *http.Request {
+	return csrfTokenKey.Set(r, token)
+}
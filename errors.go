@@ -0,0 +1,147 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// HandlerFunc is an http.HandlerFunc that may return an error. Returned errors
+// are rendered by the router's ErrorHandler.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (h HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h(w, r); err != nil {
+		Error(w, r, err)
+	}
+}
+
+// Handle adapts an error returning handler for use with the route methods.
+func Handle(handler HandlerFunc) http.HandlerFunc {
+	return handler.ServeHTTP
+}
+
+// HTTPError is an error with a status code and a message that is safe to
+// show to clients. Err holds the underlying cause and is never rendered.
+type HTTPError struct {
+	Status  int
+	Code    string
+	Message string
+	Err     error
+}
+
+func NewHTTPError(status int, code, message string) *HTTPError {
+	return &HTTPError{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func (e *HTTPError) Error() string {
+
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of e with err as its cause.
+func (e *HTTPError) Wrap(err error) *HTTPError {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+type errorResponse struct {
+	Status  int           `json:"status"`
+	Code    string        `json:"code,omitempty"`
+	Message string        `json:"message"`
+	Errors  []*FieldError `json:"errors,omitempty"`
+}
+
+// Error renders err with the ErrorHandler of the router serving r, falling
+// back to DefaultErrorHandler.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+
+	if handler, ok := r.Context().Value(contextKeyError).(ErrorHandler); ok {
+		handler(w, r, err)
+		return
+	}
+
+	DefaultErrorHandler(w, r, err)
+}
+
+// DefaultErrorHandler writes err as JSON if the client accepts it and as plain
+// text otherwise. ValidationErrors are reported as 422 with the list of
+// failing fields and *ProblemDetails keep their status. Any other error that
+// is not an *HTTPError is reported as 500 without exposing its message.
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+
+	res := errorResponse{
+		Status:  http.StatusInternalServerError,
+		Message: http.StatusText(http.StatusInternalServerError),
+	}
+
+	var httpErr *HTTPError
+	var problem *ProblemDetails
+	var validationErrs ValidationErrors
+	var fieldErrs FieldErrors
+
+	if errors.As(err, &httpErr) {
+		res.Status = httpErr.Status
+		res.Code = httpErr.Code
+		res.Message = httpErr.Message
+
+		if res.Message == "" {
+			res.Message = http.StatusText(res.Status)
+		}
+
+		if errors.As(err, &fieldErrs) {
+			res.Errors = fieldErrs
+		}
+	} else if errors.As(err, &problem) {
+		if problem.Status != 0 {
+			res.Status = problem.Status
+		}
+
+		res.Message = problem.Detail
+
+		if res.Message == "" {
+			res.Message = problem.Title
+		}
+
+		if res.Message == "" {
+			res.Message = http.StatusText(res.Status)
+		}
+	} else if errors.As(err, &validationErrs) {
+		res.Status = http.StatusUnprocessableEntity
+		res.Code = "validation_failed"
+		res.Message = "request validation failed"
+		res.Errors = validationErrs
+	}
+
+	if acceptsJSON(r) {
+		_ = JSON(w, r, res.Status, res)
+		return
+	}
+
+	http.Error(w, res.Message, res.Status)
+}
+
+func acceptsJSON(r *http.Request) bool {
+
+	accept := r.Header.Get("Accept")
+
+	return strings.Contains(accept, "application/json") || strings.Contains(accept, "+json")
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandle_HTTPErrorAsJSON(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/users/1", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+
+	r := New()
+
+	r.Get("/users/:id", Handle(func(w http.ResponseWriter, r *http.Request) error {
+		return NewHTTPError(http.StatusNotFound, "user_not_found", "user does not exist")
+	}))
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatal("response code is not 404, but ", w.Code)
+	}
+
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Error("response content type is not application/json")
+	}
+
+	expected := `{"status":404,"code":"user_not_found","message":"user does not exist"}`
+	if w.Body.String() != expected {
+		t.Error("unexpected response body: ", w.Body.String())
+	}
+}
+
+func TestHandle_ErrorAsText(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	r := New()
+
+	r.Get("/", Handle(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("database password is hunter2")
+	}))
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatal("response code is not 500, but ", w.Code)
+	}
+
+	if w.Body.String() != "Internal Server Error\n" {
+		t.Error("unexpected response body: ", w.Body.String())
+	}
+}
+
+func TestHandle_WithErrorHandler(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	cause := errors.New("cause")
+
+	var result error
+
+	r := New(WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		result = err
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	r.Get("/", Handle(func(w http.ResponseWriter, r *http.Request) error {
+		return NewHTTPError(http.StatusBadRequest, "", "").Wrap(cause)
+	}))
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusTeapot {
+		t.Fatal("response code is not 418, but ", w.Code)
+	}
+
+	if !errors.Is(result, cause) {
+		t.Error("error handler did not receive the wrapped error")
+	}
+}
@@ -0,0 +1,75 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagMatches reports whether etag matches any entity tag in list, the value
+// of an If-Match or If-None-Match header. "*" matches any etag. With strong
+// comparison weak tags never match, with weak comparison the W/ prefix is
+// ignored.
+func ETagMatches(list, etag string, strong bool) bool {
+
+	if etag == "" {
+		return false
+	}
+
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+
+	if strong && isWeakETag(etag) {
+		return false
+	}
+
+	for _, candidate := range strings.Split(list, ",") {
+
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" {
+			continue
+		}
+
+		if strong {
+			if !isWeakETag(candidate) && candidate == etag {
+				return true
+			}
+		} else if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isWeakETag(etag string) bool {
+	return strings.HasPrefix(etag, "W/")
+}
+
+// NotModified reports whether a GET or HEAD request for a representation
+// with the given validators can be answered with 304 Not Modified. As
+// required by RFC 9110, If-Modified-Since is only evaluated when the
+// request has no If-None-Match.
+func NotModified(r *http.Request, etag string, lastModified time.Time) bool {
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return ETagMatches(inm, etag, false)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(t)
+}
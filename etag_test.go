@@ -0,0 +1,54 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestETagMatches(t *testing.T) {
+
+	tests := []struct {
+		list     string
+		etag     string
+		strong   bool
+		expected bool
+	}{
+		{`"a"`, `"a"`, true, true},
+		{`"b", "a"`, `"a"`, true, true},
+		{`W/"a"`, `"a"`, true, false},
+		{`W/"a"`, `"a"`, false, true},
+		{`"a"`, `W/"a"`, false, true},
+		{`*`, `"a"`, true, true},
+		{`"b"`, `"a"`, false, false},
+		{`"a"`, ``, false, false},
+	}
+
+	for _, tt := range tests {
+		if got := ETagMatches(tt.list, tt.etag, tt.strong); got != tt.expected {
+			t.Errorf("ETagMatches(%q, %q, %v) = %v, expected %v", tt.list, tt.etag, tt.strong, got, tt.expected)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+
+	modified := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+
+	if !NotModified(req, `"a"`, modified) {
+		t.Error("expected not modified for equal Last-Modified")
+	}
+
+	if NotModified(req, `"a"`, modified.Add(time.Second)) {
+		t.Error("expected modified for later Last-Modified")
+	}
+
+	req.Header.Set("If-None-Match", `"b"`)
+
+	if NotModified(req, `"a"`, modified) {
+		t.Error("If-None-Match must take precedence over If-Modified-Since")
+	}
+}
@@ -2,9 +2,12 @@ package router
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 )
 
+// Deprecated: request locals are stored in the request context. Use Key or
+// SetLocal and GetLocal instead.
 type RequestLocals map[string]interface{}
 
 func (l RequestLocals) Get(key string) interface{} {
@@ -15,6 +18,57 @@ func (l RequestLocals) Set(key string, value interface{}) {
 	l[key] = value
 }
 
+// Key is a typed request local. Values are stored in the request context,
+// so setting a value returns a new request and never changes what other
+// requests, including the parent request, observe. This makes keys safe to
+// use from concurrent goroutines.
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns a new key. Keys are compared by identity, so two keys with
+// the same name do not collide.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+func (k *Key[T]) String() string {
+	return k.name
+}
+
+// Set returns a copy of r in which the key holds value.
+func (k *Key[T]) Set(r *http.Request, value T) *http.Request {
+	return r.WithContext(k.WithValue(r.Context(), value))
+}
+
+// WithValue returns a copy of ctx in which the key holds value.
+func (k *Key[T]) WithValue(ctx context.Context, value T) context.Context {
+	return context.WithValue(ctx, k, value)
+}
+
+// Get returns the value of the key and whether it was set.
+func (k *Key[T]) Get(r *http.Request) (T, bool) {
+	return k.Value(r.Context())
+}
+
+func (k *Key[T]) Value(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(k).(T)
+	return v, ok
+}
+
+// MustGet returns the value of the key and panics if it was not set.
+func (k *Key[T]) MustGet(r *http.Request) T {
+
+	v, ok := k.Get(r)
+	if !ok {
+		panic(fmt.Sprintf("router: request local %q is not set", k.name))
+	}
+
+	return v
+}
+
+type localKey string
+
 func SetLocal(r *http.Request, key string, value interface{}) *http.Request {
 
 	ctx := r.Context()
@@ -23,15 +77,7 @@ func SetLocal(r *http.Request, key string, value interface{}) *http.Request {
 		ctx = context.Background()
 	}
 
-	locals := ctx.Value(contextKeyLocal)
-
-	if locals == nil {
-		locals = RequestLocals{}
-	}
-
-	locals.(RequestLocals).Set(key, value)
-
-	ctx = context.WithValue(ctx, contextKeyLocal, locals)
+	ctx = context.WithValue(ctx, localKey(key), value)
 
 	return r.WithContext(ctx)
 }
@@ -41,11 +87,7 @@ func GetLocal(r *http.Request, key string) interface{} {
 	ctx := r.Context()
 
 	if ctx != nil {
-		locals := ctx.Value(contextKeyLocal)
-
-		if locals != nil {
-			return locals.(RequestLocals).Get(key)
-		}
+		return ctx.Value(localKey(key))
 	}
 
 	return nil
@@ -0,0 +1,89 @@
+package router
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+type localTestUser struct {
+	Name string
+}
+
+func TestKey(t *testing.T) {
+
+	userKey := NewKey[localTestUser]("user")
+	otherKey := NewKey[localTestUser]("user")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	if _, ok := userKey.Get(req); ok {
+		t.Fatal("expected key to be unset")
+	}
+
+	req = userKey.Set(req, localTestUser{Name: "ada"})
+
+	if u := userKey.MustGet(req); u.Name != "ada" {
+		t.Error("unexpected value: ", u)
+	}
+
+	if _, ok := otherKey.Get(req); ok {
+		t.Error("keys with the same name must not collide")
+	}
+}
+
+func TestKey_ChildDoesNotMutateParent(t *testing.T) {
+
+	key := NewKey[int]("count")
+
+	parent, _ := http.NewRequest("GET", "/", nil)
+	parent = key.Set(parent, 1)
+
+	child := key.Set(parent, 2)
+
+	if v := key.MustGet(parent); v != 1 {
+		t.Error("parent value changed to ", v)
+	}
+
+	if v := key.MustGet(child); v != 2 {
+		t.Error("unexpected child value ", v)
+	}
+
+	if GetLocal(SetLocal(child, "name", "x"), "name") != "x" || GetLocal(child, "name") != nil {
+		t.Error("SetLocal must only affect the returned request")
+	}
+}
+
+func TestKey_Concurrent(t *testing.T) {
+
+	key := NewKey[int]("n")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if v := key.MustGet(key.Set(req, i)); v != i {
+				t.Error("unexpected value ", v)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestKey_MustGetPanics(t *testing.T) {
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	NewKey[string]("missing").MustGet(req)
+}
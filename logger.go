@@ -0,0 +1,24 @@
+package router
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+var loggerKey = NewKey[*slog.Logger]("logger")
+
+// Log returns the request scoped logger set by middleware.Logger, or
+// slog.Default() if there is none.
+func Log(r *http.Request) *slog.Logger {
+
+	if l, ok := loggerKey.Get(r); ok && l != nil {
+		return l
+	}
+
+	return slog.Default()
+}
+
+// SetLogger returns a copy of r whose request scoped logger is l.
+func SetLogger(r *http.Request, l *slog.Logger) *http.Request {
+	return loggerKey.Set(r, l)
+}
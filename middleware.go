@@ -9,6 +9,7 @@ type Middleware func(http.ResponseWriter, *http.Request, http.HandlerFunc)
 type middlewareContext struct {
 	current    int
 	middleware []Middleware
+	params     *routeParams
 	final      http.HandlerFunc
 }
 
@@ -16,6 +17,10 @@ func (mc *middlewareContext) Next(w http.ResponseWriter, req *http.Request) {
 
 	if mc.current >= len(mc.middleware) {
 
+		if mc.params != nil && !mc.params.runBefore(w, req) {
+			return
+		}
+
 		if mc.final != nil {
 			mc.final(w, req)
 		}
@@ -28,3 +33,20 @@ func (mc *middlewareContext) Next(w http.ResponseWriter, req *http.Request) {
 
 	mc.middleware[c](w, req, mc.Next)
 }
+
+// BeforeHandler registers fn to run once all middleware has run, right
+// before the handler of the route that matched r. If fn returns false the
+// handler is skipped, so fn must have written the response. BeforeHandler
+// reports whether r is being served by a route; if not, fn is not
+// registered.
+func BeforeHandler(r *http.Request, fn func(w http.ResponseWriter, r *http.Request) bool) bool {
+
+	p, ok := r.Context().Value(contextKeyRoute).(*routeParams)
+	if !ok {
+		return false
+	}
+
+	p.before = append(p.before, fn)
+
+	return true
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+// APIKeyStore looks up API keys by their HashAPIKey hash, so that stores
+// never hold keys in plain text.
+type APIKeyStore interface {
+	LookupKey(ctx context.Context, hash string) (*router.Identity, error)
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash of key.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKey authenticates requests with an API key read from the X-API-Key
+// header, or the header and query parameter set by WithAPIKeyHeader and
+// WithAPIKeyQuery. The scopes of the key are the scopes of its identity.
+func APIKey(store APIKeyStore, opts ...Option) router.Middleware {
+
+	config := newConfig(opts)
+
+	credentials := func(r *http.Request) (*router.Identity, bool, error) {
+
+		key := r.Header.Get(config.APIKeyHeader)
+		if key == "" && config.APIKeyQuery != "" {
+			key = r.URL.Query().Get(config.APIKeyQuery)
+		}
+
+		if key == "" {
+			return nil, false, nil
+		}
+
+		id, err := store.LookupKey(r.Context(), HashAPIKey(key))
+
+		return id, true, err
+	}
+
+	challenge := func(bool) string {
+		return "APIKey realm=" + quote(config.Realm) + ", header=" + quote(config.APIKeyHeader)
+	}
+
+	return authenticate(config, "apikey", credentials, challenge)
+}
+
+// MemoryAPIKeyStore is an APIKeyStore held in memory.
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]*router.Identity
+}
+
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{
+		keys: make(map[string]*router.Identity),
+	}
+}
+
+// Add registers the key with the given hash for id.
+func (s *MemoryAPIKeyStore) Add(hash string, id *router.Identity) {
+	s.mu.Lock()
+	s.keys[hash] = id
+	s.mu.Unlock()
+}
+
+func (s *MemoryAPIKeyStore) Remove(hash string) {
+	s.mu.Lock()
+	delete(s.keys, hash)
+	s.mu.Unlock()
+}
+
+func (s *MemoryAPIKeyStore) LookupKey(ctx context.Context, hash string) (*router.Identity, error) {
+
+	s.mu.RLock()
+	id, ok := s.keys[hash]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	return id, nil
+}
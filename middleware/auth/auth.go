@@ -0,0 +1,159 @@
+// Package auth provides authentication middleware. Each middleware stores
+// the authenticated principal with router.SetPrincipal, so handlers and
+// later middleware read it with router.Principal.
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+var (
+	ErrUnauthorized       = router.ErrUnauthorized
+	ErrForbidden          = router.ErrForbidden
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+)
+
+type Option func(*Config)
+
+type Config struct {
+	Realm    string
+	Optional bool
+
+	// APIKeyHeader and APIKeyQuery name where APIKey looks for the key.
+	APIKeyHeader string
+	APIKeyQuery  string
+}
+
+func WithRealm(realm string) Option {
+	return func(c *Config) {
+		c.Realm = realm
+	}
+}
+
+// WithOptional lets requests without credentials through anonymously.
+// Requests with invalid credentials are still rejected.
+func WithOptional() Option {
+	return func(c *Config) {
+		c.Optional = true
+	}
+}
+
+// WithAPIKeyHeader sets the header APIKey reads the key from. The default
+// is X-API-Key.
+func WithAPIKeyHeader(name string) Option {
+	return func(c *Config) {
+		c.APIKeyHeader = name
+	}
+}
+
+// WithAPIKeyQuery lets APIKey read the key from the named query parameter
+// if the header is not set. Keys in URLs end up in logs, so this is off by
+// default.
+func WithAPIKeyQuery(name string) Option {
+	return func(c *Config) {
+		c.APIKeyQuery = name
+	}
+}
+
+func newConfig(opts []Option) *Config {
+
+	config := &Config{
+		Realm:        "restricted",
+		APIKeyHeader: "X-API-Key",
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return config
+}
+
+// credentials extracts and verifies the credentials of r. present is false
+// if r carries none.
+type credentials func(r *http.Request) (id *router.Identity, present bool, err error)
+
+// authenticate returns middleware that verifies credentials and rejects the
+// request with a challenge built by challenge. Verifiers report bad
+// credentials with ErrInvalidCredentials; other errors are passed to
+// router.Error. Requests that already have a principal are let through, so
+// several schemes can be chained with WithOptional.
+func authenticate(config *Config, scheme string, verify credentials, challenge func(invalid bool) string) router.Middleware {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+
+		if router.Principal(r) != nil {
+			next(w, r)
+			return
+		}
+
+		id, present, err := verify(r)
+
+		if !present {
+			if config.Optional {
+				next(w, r)
+				return
+			}
+
+			w.Header().Add("WWW-Authenticate", challenge(false))
+			router.Error(w, r, ErrUnauthorized)
+			return
+		}
+
+		if err != nil && !errors.Is(err, ErrInvalidCredentials) {
+			router.Error(w, r, err)
+			return
+		}
+
+		if err != nil || id == nil {
+			w.Header().Add("WWW-Authenticate", challenge(true))
+			router.Error(w, r, ErrUnauthorized)
+			return
+		}
+
+		if id.Scheme == "" {
+			c := *id
+			c.Scheme = scheme
+			id = &c
+		}
+
+		next(w, router.SetPrincipal(r, id))
+	}
+}
+
+// RequireScopes rejects requests whose principal lacks any of scopes with
+// 403, or with 401 if the request is anonymous. Use it on a route or group
+// after the authentication middleware.
+func RequireScopes(scopes ...string) router.Middleware {
+
+	scope := strconv.Quote(strings.Join(scopes, " "))
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+
+		id := router.Principal(r)
+
+		if id == nil {
+			w.Header().Add("WWW-Authenticate", "Bearer scope="+scope)
+			router.Error(w, r, ErrUnauthorized)
+			return
+		}
+
+		for _, s := range scopes {
+			if !id.HasScope(s) {
+				w.Header().Add("WWW-Authenticate", `Bearer error="insufficient_scope", scope=`+scope)
+				router.Error(w, r, ErrForbidden)
+				return
+			}
+		}
+
+		next(w, r)
+	}
+}
+
+func quote(s string) string {
+	return strconv.Quote(s)
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+func serve(m router.Middleware, req *http.Request) (*httptest.ResponseRecorder, *router.Identity) {
+
+	var principal *router.Identity
+
+	w := httptest.NewRecorder()
+	m(w, req, func(w http.ResponseWriter, r *http.Request) {
+		principal = router.Principal(r)
+	})
+
+	return w, principal
+}
+
+func TestBasic(t *testing.T) {
+
+	m := Basic(BasicUsers(map[string]string{"admin": "secret"}), WithRealm("admin"))
+
+	tests := []struct {
+		user, password string
+		status         int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"admin", "wrong", http.StatusUnauthorized},
+		{"nobody", "secret", http.StatusUnauthorized},
+		{"admin", "secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		if tt.user != "" {
+			req.SetBasicAuth(tt.user, tt.password)
+		}
+
+		w, id := serve(m, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.user, tt.status, w.Code)
+		}
+
+		if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != `Basic realm="admin", charset="UTF-8"` {
+			t.Errorf("%s: unexpected challenge %q", tt.user, w.Header().Get("WWW-Authenticate"))
+		}
+
+		if tt.status == http.StatusOK && (id == nil || id.Subject != "admin" || id.Scheme != "basic") {
+			t.Errorf("%s: unexpected principal %v", tt.user, id)
+		}
+	}
+}
+
+func TestBearer(t *testing.T) {
+
+	m := Bearer(func(ctx context.Context, token string) (*router.Identity, error) {
+		if token != "good" {
+			return nil, ErrInvalidCredentials
+		}
+		return &router.Identity{Subject: "user", Scopes: []string{"read"}}, nil
+	}, WithOptional())
+
+	tests := []struct {
+		authorization string
+		status        int
+		challenge     string
+		authenticated bool
+	}{
+		{"", http.StatusOK, "", false},
+		{"Bearer bad", http.StatusUnauthorized, `Bearer realm="restricted", error="invalid_token"`, false},
+		{"bearer good", http.StatusOK, "", true},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", tt.authorization)
+
+		w, id := serve(m, req)
+
+		if w.Code != tt.status || w.Header().Get("WWW-Authenticate") != tt.challenge || (id != nil) != tt.authenticated {
+			t.Errorf("%q: unexpected response %d %q %v", tt.authorization, w.Code, w.Header().Get("WWW-Authenticate"), id)
+		}
+	}
+}
+
+func TestBasicThenBearer(t *testing.T) {
+
+	basic := Basic(BasicUsers(map[string]string{"admin": "secret"}), WithOptional())
+	bearer := Bearer(func(ctx context.Context, token string) (*router.Identity, error) {
+		if token != "good" {
+			return nil, ErrInvalidCredentials
+		}
+		return &router.Identity{Subject: "user"}, nil
+	})
+
+	chain := func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		basic(w, r, func(w http.ResponseWriter, r *http.Request) {
+			bearer(w, r, next)
+		})
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer good")
+
+	w, id := serve(chain, req)
+
+	if w.Code != http.StatusOK || id == nil || id.Scheme != "bearer" {
+		t.Errorf("expected bearer authentication through basic, got %d %v", w.Code, id)
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("admin", "secret")
+
+	w, id = serve(chain, req)
+
+	if w.Code != http.StatusOK || id == nil || id.Scheme != "basic" {
+		t.Errorf("expected basic authentication, got %d %v", w.Code, id)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+
+	store := NewMemoryAPIKeyStore()
+	store.Add(HashAPIKey("k1"), &router.Identity{Subject: "svc", Scopes: []string{"read"}})
+
+	m := APIKey(store, WithAPIKeyQuery("api_key"))
+
+	tests := []struct {
+		header, query string
+		status        int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"k2", "", http.StatusUnauthorized},
+		{"k1", "", http.StatusOK},
+		{"", "k1", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest("GET", "/?api_key="+tt.query, nil)
+		req.Header.Set("X-API-Key", tt.header)
+
+		w, id := serve(m, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%q %q: expected %d, got %d", tt.header, tt.query, tt.status, w.Code)
+		}
+
+		if tt.status == http.StatusOK && (id == nil || id.Subject != "svc" || id.Scheme != "apikey") {
+			t.Errorf("%q %q: unexpected principal %v", tt.header, tt.query, id)
+		}
+	}
+}
+
+func TestRequireScopes(t *testing.T) {
+
+	r := router.New()
+
+	r.Use(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if r.Header.Get("X-User") != "" {
+			r = router.SetPrincipal(r, &router.Identity{Subject: "user", Scopes: []string{"users:read"}})
+		}
+		next(w, r)
+	})
+
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request) {}).Use(RequireScopes("users:read"))
+	r.Delete("/users", func(w http.ResponseWriter, r *http.Request) {}).Use(RequireScopes("users:write"))
+
+	tests := []struct {
+		method string
+		user   bool
+		status int
+	}{
+		{"GET", false, http.StatusUnauthorized},
+		{"GET", true, http.StatusOK},
+		{"DELETE", true, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest(tt.method, "/users", nil)
+		if tt.user {
+			req.Header.Set("X-User", "1")
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s user=%v: expected %d, got %d", tt.method, tt.user, tt.status, w.Code)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+// BasicVerifier returns the identity of a user, or ErrInvalidCredentials.
+type BasicVerifier func(ctx context.Context, username, password string) (*router.Identity, error)
+
+// Basic authenticates requests with HTTP Basic authentication.
+func Basic(verify BasicVerifier, opts ...Option) router.Middleware {
+
+	config := newConfig(opts)
+
+	credentials := func(r *http.Request) (*router.Identity, bool, error) {
+
+		// Other schemes are left to the middleware that handles them, so
+		// that Basic can be chained with Bearer.
+		scheme, _, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Basic") {
+			return nil, false, nil
+		}
+
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			return nil, true, ErrInvalidCredentials
+		}
+
+		id, err := verify(r.Context(), username, password)
+
+		return id, true, err
+	}
+
+	challenge := func(bool) string {
+		return "Basic realm=" + quote(config.Realm) + `, charset="UTF-8"`
+	}
+
+	return authenticate(config, "basic", credentials, challenge)
+}
+
+// BasicUsers returns a verifier for a fixed set of users and passwords. The
+// comparison takes the same time whether or not the user exists.
+func BasicUsers(users map[string]string) BasicVerifier {
+
+	hashes := make(map[string][32]byte, len(users))
+	for username, password := range users {
+		hashes[username] = sha256.Sum256([]byte(password))
+	}
+
+	return func(ctx context.Context, username, password string) (*router.Identity, error) {
+
+		expected, exists := hashes[username]
+		actual := sha256.Sum256([]byte(password))
+
+		if subtle.ConstantTimeCompare(expected[:], actual[:]) != 1 || !exists {
+			return nil, ErrInvalidCredentials
+		}
+
+		return &router.Identity{Subject: username}, nil
+	}
+}
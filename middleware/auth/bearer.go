@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+// TokenVerifier returns the identity a bearer token was issued to, or
+// ErrInvalidCredentials.
+type TokenVerifier func(ctx context.Context, token string) (*router.Identity, error)
+
+// Bearer authenticates requests with a bearer token in the Authorization
+// header, as described in RFC 6750.
+func Bearer(verify TokenVerifier, opts ...Option) router.Middleware {
+
+	config := newConfig(opts)
+
+	credentials := func(r *http.Request) (*router.Identity, bool, error) {
+
+		token, ok := BearerToken(r)
+		if !ok {
+			return nil, false, nil
+		}
+
+		id, err := verify(r.Context(), token)
+
+		return id, true, err
+	}
+
+	challenge := func(invalid bool) string {
+
+		c := "Bearer realm=" + quote(config.Realm)
+		if invalid {
+			c += `, error="invalid_token"`
+		}
+
+		return c
+	}
+
+	return authenticate(config, "bearer", credentials, challenge)
+}
+
+// BearerToken returns the bearer token in the Authorization header of r.
+func BearerToken(r *http.Request) (string, bool) {
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+
+	return token, token != ""
+}
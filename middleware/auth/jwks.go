@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWKS is a KeySet fetched from a JSON Web Key Set URL. Keys are cached and
+// refetched after RefreshInterval, or when a token names an unknown key ID,
+// which is how rotated keys are picked up. Unknown key IDs and failed fetches
+// trigger at most one fetch per MinRefreshInterval.
+type JWKS struct {
+	URL string
+
+	// Client is used to fetch the key set. http.DefaultClient is used if it
+	// is nil.
+	Client *http.Client
+
+	// RefreshInterval defaults to one hour and MinRefreshInterval to one
+	// minute.
+	RefreshInterval    time.Duration
+	MinRefreshInterval time.Duration
+
+	// Timeout limits each fetch and defaults to ten seconds. Fetches are not
+	// cancelled with the request that triggered them, as other requests
+	// wait for the result.
+	Timeout time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]any
+	fetched   time.Time
+	attempted time.Time
+	err       error
+}
+
+func (j *JWKS) Key(ctx context.Context, kid, alg string) (any, error) {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	refresh := j.RefreshInterval
+	if refresh == 0 {
+		refresh = time.Hour
+	}
+
+	minRefresh := j.MinRefreshInterval
+	if minRefresh == 0 {
+		minRefresh = time.Minute
+	}
+
+	if key, ok := j.keys[kid]; ok && time.Since(j.fetched) < refresh {
+		return key, nil
+	}
+
+	if j.attempted.IsZero() || time.Since(j.attempted) >= minRefresh {
+		j.attempted = time.Now()
+		j.err = j.fetch(ctx)
+	}
+
+	if j.err != nil {
+		// Keep serving the cached keys if the endpoint is down.
+		if key, ok := j.keys[kid]; ok {
+			return key, nil
+		}
+
+		return nil, j.err
+	}
+
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return key, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (j *JWKS) fetch(ctx context.Context) error {
+
+	client := j.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	timeout := j.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth: fetching JWKS: unexpected status %d", res.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return fmt.Errorf("auth: decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+
+	for _, k := range set.Keys {
+
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		// Keys of unsupported types are skipped rather than failing the
+		// whole set.
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+
+	j.keys = keys
+	j.fetched = time.Now()
+
+	return nil
+}
+
+func (k jwk) publicKey() (any, error) {
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("auth: RSA exponent too large")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, errors.New("auth: unsupported curve " + k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("auth: malformed EC key")
+		}
+
+		// Parsing the uncompressed point checks that it is on the curve.
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, errors.New("auth: unsupported curve " + k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("auth: malformed Ed25519 key")
+		}
+
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, errors.New("auth: unsupported key type " + k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
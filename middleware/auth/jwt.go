@@ -0,0 +1,394 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+var ErrUnknownKey = errors.New("auth: unknown signing key")
+
+// KeySet returns the key that verifies tokens signed with alg by the key
+// with the given ID. The key is a []byte for HS256, an *rsa.PublicKey for
+// RS256, an *ecdsa.PublicKey for ES256 and an ed25519.PublicKey for EdDSA.
+type KeySet interface {
+	Key(ctx context.Context, kid, alg string) (any, error)
+}
+
+// StaticKeys is a KeySet of fixed keys by key ID. The key with the empty ID
+// verifies tokens without a kid header.
+type StaticKeys map[string]any
+
+func (k StaticKeys) Key(ctx context.Context, kid, alg string) (any, error) {
+
+	key, ok := k[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return key, nil
+}
+
+// Claims are the claims of a verified JWT.
+type Claims map[string]any
+
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+func (c Claims) Issuer() string {
+	return c.String("iss")
+}
+
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// Audience returns the aud claim, which may be a string or an array.
+func (c Claims) Audience() []string {
+	return c.strings("aud")
+}
+
+func (c Claims) ExpiresAt() time.Time {
+	return c.Time("exp")
+}
+
+func (c Claims) NotBefore() time.Time {
+	return c.Time("nbf")
+}
+
+func (c Claims) IssuedAt() time.Time {
+	return c.Time("iat")
+}
+
+// Time returns the NumericDate claim name, or the zero time.
+func (c Claims) Time(name string) time.Time {
+
+	var seconds float64
+
+	switch v := c[name].(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}
+		}
+		seconds = f
+	case float64:
+		seconds = v
+	default:
+		return time.Time{}
+	}
+
+	sec, frac := math.Modf(seconds)
+
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// Scopes returns the space separated scope claim, or the scp claim used by
+// some issuers instead.
+func (c Claims) Scopes() []string {
+
+	if scope := c.String("scope"); scope != "" {
+		return strings.Fields(scope)
+	}
+
+	return c.strings("scp")
+}
+
+func (c Claims) Roles() []string {
+	return c.strings("roles")
+}
+
+func (c Claims) strings(name string) []string {
+
+	switch v := c[name].(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return strings.Fields(v)
+	case []any:
+		var s []string
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				s = append(s, str)
+			}
+		}
+		return s
+	}
+
+	return nil
+}
+
+// JWTClaims returns the claims of the JWT that authenticated r, or nil.
+func JWTClaims(r *http.Request) Claims {
+
+	id := router.Principal(r)
+	if id == nil || id.Scheme != "jwt" {
+		return nil
+	}
+
+	return Claims(id.Attributes)
+}
+
+type JWTOption func(*JWTVerifier)
+
+// WithIssuer requires the iss claim to be issuer.
+func WithIssuer(issuer string) JWTOption {
+	return func(v *JWTVerifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience requires the aud claim to contain audience.
+func WithAudience(audience string) JWTOption {
+	return func(v *JWTVerifier) {
+		v.audience = audience
+	}
+}
+
+// WithLeeway sets the clock skew tolerated when checking exp and nbf. The
+// default is one minute.
+func WithLeeway(leeway time.Duration) JWTOption {
+	return func(v *JWTVerifier) {
+		v.leeway = leeway
+	}
+}
+
+// WithRequireExpiration rejects tokens without an exp claim, which are
+// otherwise valid forever.
+func WithRequireExpiration() JWTOption {
+	return func(v *JWTVerifier) {
+		v.requireExp = true
+	}
+}
+
+// WithAlgorithms restricts the accepted signing algorithms.
+func WithAlgorithms(algs ...string) JWTOption {
+	return func(v *JWTVerifier) {
+		v.algorithms = algs
+	}
+}
+
+// JWTVerifier verifies signed JWTs in compact serialization.
+type JWTVerifier struct {
+	keys       KeySet
+	issuer     string
+	audience   string
+	leeway     time.Duration
+	algorithms []string
+	requireExp bool
+}
+
+func NewJWTVerifier(keys KeySet, opts ...JWTOption) *JWTVerifier {
+
+	v := &JWTVerifier{
+		keys:       keys,
+		leeway:     time.Minute,
+		algorithms: []string{"HS256", "RS256", "ES256", "EdDSA"},
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks the signature and the exp, nbf, iss and aud claims of token
+// and returns its claims. Invalid tokens are reported with an error wrapping
+// ErrInvalidCredentials.
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (Claims, error) {
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalidToken("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, invalidToken("malformed header")
+	}
+
+	if !slices.Contains(v.algorithms, header.Alg) {
+		return nil, invalidToken("unsupported algorithm " + header.Alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalidToken("malformed signature")
+	}
+
+	key, err := v.keys.Key(ctx, header.Kid, header.Alg)
+	if errors.Is(err, ErrUnknownKey) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, invalidToken("malformed claims")
+	}
+
+	if err := v.validate(claims, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *JWTVerifier) validate(claims Claims, now time.Time) error {
+
+	if _, ok := claims["exp"]; ok {
+		exp := claims.ExpiresAt()
+		if exp.IsZero() || now.After(exp.Add(v.leeway)) {
+			return invalidToken("token expired")
+		}
+	} else if v.requireExp {
+		return invalidToken("token has no expiration")
+	}
+
+	if _, ok := claims["nbf"]; ok {
+		nbf := claims.NotBefore()
+		if nbf.IsZero() || now.Add(v.leeway).Before(nbf) {
+			return invalidToken("token not valid yet")
+		}
+	}
+
+	if v.issuer != "" && claims.Issuer() != v.issuer {
+		return invalidToken("unexpected issuer")
+	}
+
+	if v.audience != "" && !slices.Contains(claims.Audience(), v.audience) {
+		return invalidToken("unexpected audience")
+	}
+
+	return nil
+}
+
+func verifySignature(alg string, key any, signed string, sig []byte) error {
+
+	hash := sha256.Sum256([]byte(signed))
+
+	var ok bool
+
+	switch alg {
+	case "HS256":
+		secret, isSecret := key.([]byte)
+		if !isSecret {
+			return invalidToken("key does not match algorithm")
+		}
+
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		ok = hmac.Equal(sig, mac.Sum(nil))
+
+	case "RS256":
+		pub, isRSA := key.(*rsa.PublicKey)
+		if !isRSA {
+			return invalidToken("key does not match algorithm")
+		}
+
+		ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig) == nil
+
+	case "ES256":
+		pub, isECDSA := key.(*ecdsa.PublicKey)
+		if !isECDSA || pub.Curve.Params().BitSize != 256 {
+			return invalidToken("key does not match algorithm")
+		}
+
+		if len(sig) != 64 {
+			return invalidToken("malformed signature")
+		}
+
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		ok = ecdsa.Verify(pub, hash[:], r, s)
+
+	case "EdDSA":
+		pub, isEd25519 := key.(ed25519.PublicKey)
+		if !isEd25519 {
+			return invalidToken("key does not match algorithm")
+		}
+
+		ok = ed25519.Verify(pub, []byte(signed), sig)
+
+	default:
+		return invalidToken("unsupported algorithm " + alg)
+	}
+
+	if !ok {
+		return invalidToken("invalid signature")
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	return dec.Decode(v)
+}
+
+func invalidToken(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidCredentials, reason)
+}
+
+// JWT authenticates requests with a bearer JWT verified by v. The principal
+// has the sub claim as its subject, the scope and roles claims as its scopes
+// and roles, and all claims as its attributes. Use RequireScopes to require
+// scopes per route, and JWTClaims to read the claims.
+func JWT(v *JWTVerifier, opts ...Option) router.Middleware {
+	return Bearer(func(ctx context.Context, token string) (*router.Identity, error) {
+
+		claims, err := v.Verify(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		return &router.Identity{
+			Subject:    claims.Subject(),
+			Scheme:     "jwt",
+			Scopes:     claims.Scopes(),
+			Roles:      claims.Roles(),
+			Attributes: claims,
+		}, nil
+	}, opts...)
+}
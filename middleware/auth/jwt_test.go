@@ -0,0 +1,276 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+func signJWT(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	var err error
+
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "RS256":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, hash[:])
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), hash[:])
+		if err == nil {
+			sig = make([]byte, 64)
+			r.FillBytes(sig[:32])
+			s.FillBytes(sig[32:])
+		}
+	case "EdDSA":
+		sig = ed25519.Sign(key.(ed25519.PrivateKey), []byte(signed))
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTVerifier(t *testing.T) {
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	secret := []byte("secret")
+
+	v := NewJWTVerifier(StaticKeys{
+		"hs": secret,
+		"rs": &rsaKey.PublicKey,
+		"es": &ecKey.PublicKey,
+		"ed": edPub,
+	}, WithIssuer("issuer"), WithAudience("api"), WithLeeway(10*time.Second))
+
+	now := time.Now().Unix()
+
+	valid := map[string]any{"iss": "issuer", "aud": []string{"web", "api"}, "sub": "user", "exp": now + 60}
+
+	tests := []struct {
+		name   string
+		alg    string
+		kid    string
+		key    any
+		claims map[string]any
+		valid  bool
+	}{
+		{"HS256", "HS256", "hs", secret, valid, true},
+		{"RS256", "RS256", "rs", rsaKey, valid, true},
+		{"ES256", "ES256", "es", ecKey, valid, true},
+		{"EdDSA", "EdDSA", "ed", edKey, valid, true},
+		{"wrong key", "HS256", "hs", []byte("other"), valid, false},
+		{"algorithm confusion", "HS256", "rs", secret, valid, false},
+		{"unknown kid", "HS256", "xx", secret, valid, false},
+		{"leeway", "HS256", "hs", secret, map[string]any{"iss": "issuer", "aud": "api", "exp": now - 5}, true},
+		{"expired", "HS256", "hs", secret, map[string]any{"iss": "issuer", "aud": "api", "exp": now - 60}, false},
+		{"not before", "HS256", "hs", secret, map[string]any{"iss": "issuer", "aud": "api", "nbf": now + 60}, false},
+		{"issuer", "HS256", "hs", secret, map[string]any{"iss": "other", "aud": "api"}, false},
+		{"audience", "HS256", "hs", secret, map[string]any{"iss": "issuer", "aud": "web"}, false},
+	}
+
+	for _, tt := range tests {
+
+		token := signJWT(t, tt.alg, tt.kid, tt.key, tt.claims)
+
+		claims, err := v.Verify(context.Background(), token)
+
+		if tt.valid && (err != nil || claims.Subject() != tt.claims["sub"] && tt.claims["sub"] != nil) {
+			t.Errorf("%s: expected valid token, got %v", tt.name, err)
+		}
+
+		if !tt.valid && !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: expected invalid token, got %v", tt.name, err)
+		}
+	}
+
+	if _, err := v.Verify(context.Background(), "a.b"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Error("expected malformed token to be invalid")
+	}
+}
+
+func TestJWTVerifierRequireExpiration(t *testing.T) {
+
+	secret := []byte("secret")
+	v := NewJWTVerifier(StaticKeys{"hs": secret}, WithRequireExpiration())
+
+	if _, err := v.Verify(context.Background(), signJWT(t, "HS256", "hs", secret, map[string]any{"sub": "user"})); !errors.Is(err, ErrInvalidCredentials) {
+		t.Error("expected token without exp to be invalid, got ", err)
+	}
+
+	claims := map[string]any{"sub": "user", "exp": time.Now().Unix() + 60}
+
+	if _, err := v.Verify(context.Background(), signJWT(t, "HS256", "hs", secret, claims)); err != nil {
+		t.Error("expected token with exp to be valid, got ", err)
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+
+	key1, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	key2, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	current := atomic.Pointer[ecdsa.PrivateKey]{}
+	current.Store(key1)
+
+	var fetches atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+
+		kid := "k1"
+		if current.Load() == key2 {
+			kid = "k2"
+		}
+
+		pub := current.Load().PublicKey
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "EC",
+				"crv": "P-256",
+				"kid": kid,
+				"use": "sig",
+				"x":   base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, 32))),
+				"y":   base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, 32))),
+			}},
+		})
+	}))
+	defer srv.Close()
+
+	jwks := &JWKS{URL: srv.URL, Client: srv.Client(), MinRefreshInterval: time.Nanosecond}
+	v := NewJWTVerifier(jwks)
+
+	claims := map[string]any{"sub": "user"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := v.Verify(context.Background(), signJWT(t, "ES256", "k1", key1, claims)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if fetches.Load() != 1 {
+		t.Fatal("expected cached keys to be reused, fetches: ", fetches.Load())
+	}
+
+	current.Store(key2)
+
+	if _, err := v.Verify(context.Background(), signJWT(t, "ES256", "k2", key2, claims)); err != nil {
+		t.Fatal("expected rotated key to be fetched: ", err)
+	}
+
+	if fetches.Load() != 2 {
+		t.Error("expected a refetch for the unknown kid, fetches: ", fetches.Load())
+	}
+}
+
+func TestJWKSFetchFailure(t *testing.T) {
+
+	var fetches atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	jwks := &JWKS{URL: srv.URL, Client: srv.Client()}
+
+	for i := 0; i < 3; i++ {
+		if _, err := jwks.Key(context.Background(), "k1", "ES256"); err == nil {
+			t.Fatal("expected the failed fetch to be reported")
+		}
+	}
+
+	if fetches.Load() != 1 {
+		t.Error("expected failed fetches to respect MinRefreshInterval, fetches: ", fetches.Load())
+	}
+}
+
+func TestJWKSDetachedFetch(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"keys":[]}`))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	jwks := &JWKS{URL: srv.URL, Client: srv.Client()}
+
+	if _, err := jwks.Key(ctx, "k1", "ES256"); err != ErrUnknownKey {
+		t.Error("expected the fetch to ignore the request's cancellation, got ", err)
+	}
+}
+
+func TestJWT(t *testing.T) {
+
+	secret := []byte("secret")
+
+	r := router.New()
+	r.Use(JWT(NewJWTVerifier(StaticKeys{"": secret})))
+
+	r.Delete("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(JWTClaims(r).String("tenant")))
+	}).Use(RequireScopes("users:delete"))
+
+	tests := []struct {
+		scope  string
+		status int
+	}{
+		{"users:read", http.StatusForbidden},
+		{"users:read users:delete", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+
+		token := signJWT(t, "HS256", "", secret, map[string]any{"sub": "user", "scope": tt.scope, "tenant": "acme"})
+
+		req, _ := http.NewRequest("DELETE", "/users/1", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%q: expected %d, got %d", tt.scope, tt.status, w.Code)
+		}
+
+		if tt.status == http.StatusOK && w.Body.String() != "acme" {
+			t.Errorf("%q: expected claims in request, got %q", tt.scope, w.Body.String())
+		}
+	}
+}
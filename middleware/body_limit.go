@@ -0,0 +1,235 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+// limitedBody is the body installed by BodyLimit. It remembers the original
+// body so that a more specific BodyLimit, such as one on a route, can
+// replace the limit of an outer one while the body is still unread.
+type limitedBody struct {
+	io.ReadCloser
+	orig     io.ReadCloser
+	w        http.ResponseWriter
+	limit    int64
+	tooLarge bool
+	read     bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+
+	b.read = true
+
+	if b.tooLarge {
+		b.w.Header().Set("Connection", "close")
+		return 0, &http.MaxBytesError{Limit: b.limit}
+	}
+
+	return b.ReadCloser.Read(p)
+}
+
+func (b *limitedBody) setLimit(w http.ResponseWriter, contentLength, n int64) {
+	b.ReadCloser = http.MaxBytesReader(w, b.orig, n)
+	b.w = w
+	b.limit = n
+	b.tooLarge = contentLength > n
+}
+
+// check rejects the request with 413 before the handler runs if its
+// Content-Length exceeds the limit in place at that point.
+func (b *limitedBody) check(w http.ResponseWriter, r *http.Request) bool {
+
+	if b.read || !b.tooLarge {
+		return true
+	}
+
+	w.Header().Set("Connection", "close")
+	router.Error(w, r, router.NewHTTPError(http.StatusRequestEntityTooLarge, "request_too_large", "request body too large").
+		Wrap(&http.MaxBytesError{Limit: b.limit}))
+
+	return false
+}
+
+// BodyLimit limits request bodies to n bytes. Requests whose Content-Length
+// exceeds n are rejected with 413 before the handler runs and before any of
+// the body is read; otherwise reading past the limit fails with
+// *http.MaxBytesError, which router.Bind reports as 413.
+//
+// BodyLimit on a group or route overrides the limit of a BodyLimit on the
+// router, so upload endpoints can allow larger bodies. Outside of a route,
+// the Content-Length is checked on the first Read instead.
+func BodyLimit(n int64) router.Middleware {
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+
+		if r.Body == nil || r.Body == http.NoBody {
+			next(w, r)
+			return
+		}
+
+		if b, ok := r.Body.(*limitedBody); ok && !b.read {
+			b.setLimit(w, r.ContentLength, n)
+			next(w, r)
+			return
+		}
+
+		b := &limitedBody{orig: r.Body}
+		b.setLimit(w, r.ContentLength, n)
+
+		r.Body = b
+		router.BeforeHandler(r, b.check)
+
+		next(w, r)
+	}
+}
+
+type DecompressOption func(*DecompressConfig)
+
+type DecompressConfig struct {
+	MaxRatio int64
+	MaxSize  int64
+}
+
+// WithMaxRatio limits how many times larger the decompressed body may be
+// than the compressed body.
+func WithMaxRatio(ratio int64) DecompressOption {
+	return func(c *DecompressConfig) {
+		c.MaxRatio = ratio
+	}
+}
+
+// WithMaxSize limits the size of the decompressed body.
+func WithMaxSize(n int64) DecompressOption {
+	return func(c *DecompressConfig) {
+		c.MaxSize = n
+	}
+}
+
+// Decompress transparently decodes gzip and deflate request bodies. To
+// guard against decompression bombs, reading fails with *http.MaxBytesError
+// once the body expands by more than the configured ratio (100 by default)
+// or size. Unsupported encodings are rejected with 415.
+func Decompress(opts ...DecompressOption) router.Middleware {
+
+	config := &DecompressConfig{
+		MaxRatio: 100,
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+
+		if encoding == "" || encoding == "identity" || r.Body == nil || r.Body == http.NoBody {
+			next(w, r)
+			return
+		}
+
+		if encoding != "gzip" && encoding != "deflate" {
+			router.Error(w, r, router.ErrUnsupportedMediaType)
+			return
+		}
+
+		compressed := &countingReader{r: r.Body}
+
+		r.Body = &decompressBody{
+			encoding:   encoding,
+			compressed: compressed,
+			closer:     r.Body,
+			config:     config,
+		}
+
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+
+		next(w, r)
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// minRatioBase avoids rejecting small, highly compressible bodies whose
+// ratio is large only because they are small.
+const minRatioBase = 1024
+
+type decompressBody struct {
+	encoding   string
+	compressed *countingReader
+	closer     io.Closer
+	config     *DecompressConfig
+	reader     io.ReadCloser
+	n          int64
+	err        error
+}
+
+func (d *decompressBody) Read(p []byte) (int, error) {
+
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	if d.reader == nil {
+		var err error
+
+		if d.encoding == "gzip" {
+			d.reader, err = gzip.NewReader(d.compressed)
+		} else {
+			d.reader, err = zlib.NewReader(d.compressed)
+		}
+
+		if err != nil {
+			d.err = err
+			return 0, err
+		}
+	}
+
+	n, err := d.reader.Read(p)
+	d.n += int64(n)
+
+	if d.config.MaxSize > 0 && d.n > d.config.MaxSize {
+		d.err = &http.MaxBytesError{Limit: d.config.MaxSize}
+		return 0, d.err
+	}
+
+	if d.config.MaxRatio > 0 {
+		base := d.compressed.n
+		if base < minRatioBase {
+			base = minRatioBase
+		}
+
+		if d.n > base*d.config.MaxRatio {
+			d.err = &http.MaxBytesError{Limit: base * d.config.MaxRatio}
+			return 0, d.err
+		}
+	}
+
+	return n, err
+}
+
+func (d *decompressBody) Close() error {
+
+	if d.reader != nil {
+		d.reader.Close()
+	}
+
+	return d.closer.Close()
+}
@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+func TestBodyLimit(t *testing.T) {
+
+	r := router.New()
+	r.Use(BodyLimit(8))
+
+	bind := router.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		var v struct {
+			Name string `json:"name"`
+		}
+		return router.Bind(r, &v)
+	})
+
+	r.Post("/small", bind)
+	r.Post("/upload", bind).Use(BodyLimit(64))
+
+	tests := []struct {
+		path    string
+		body    string
+		chunked bool
+		status  int
+	}{
+		{"/small", `{}`, false, http.StatusOK},
+		{"/small", `{"name":"too long"}`, false, http.StatusRequestEntityTooLarge},
+		{"/small", `{"name":"too long"}`, true, http.StatusRequestEntityTooLarge},
+		{"/upload", `{"name":"too long"}`, false, http.StatusOK},
+		{"/upload", `{"name":"too long"}`, true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.chunked {
+			req.ContentLength = -1
+		}
+
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s %q (chunked %v): expected status %d, got %d", tt.path, tt.body, tt.chunked, tt.status, w.Code)
+		}
+	}
+}
+
+func TestBodyLimitRejectsBeforeHandler(t *testing.T) {
+
+	var called []string
+
+	r := router.New()
+	r.Use(BodyLimit(8))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		called = append(called, r.URL.Path)
+	}
+
+	r.Post("/small", handler)
+	r.Post("/upload", handler).Use(BodyLimit(64))
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/small", http.StatusRequestEntityTooLarge},
+		{"/upload", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+
+		called = nil
+
+		req, _ := http.NewRequest("POST", tt.path, strings.NewReader(`{"name":"too long"}`))
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, w.Code)
+		}
+
+		if handled := len(called) == 1; handled != (tt.status == http.StatusOK) {
+			t.Errorf("%s: unexpected handler calls %v", tt.path, called)
+		}
+	}
+}
+
+func TestBodyLimitContentLengthNotRead(t *testing.T) {
+
+	body := &countingReader{r: strings.NewReader("this is too long")}
+
+	req, _ := http.NewRequest("POST", "/", body)
+	req.ContentLength = 16
+	w := httptest.NewRecorder()
+
+	var err error
+
+	BodyLimit(8)(w, req, func(w http.ResponseWriter, r *http.Request) {
+		_, err = io.ReadAll(r.Body)
+	})
+
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		t.Fatal("expected MaxBytesError, got ", err)
+	}
+
+	if body.n != 0 {
+		t.Error("expected the body not to be read, read ", body.n)
+	}
+
+	if w.Header().Get("Connection") != "close" {
+		t.Error("expected Connection: close")
+	}
+}
+
+func gzipBody(data []byte) *bytes.Buffer {
+
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+	_, _ = gw.Write(data)
+	_ = gw.Close()
+
+	return &buf
+}
+
+func TestDecompress(t *testing.T) {
+
+	req, _ := http.NewRequest("POST", "/", gzipBody([]byte(`{"name":"ada"}`)))
+	req.Header.Set("Content-Encoding", "gzip")
+
+	var body []byte
+
+	Decompress()(httptest.NewRecorder(), req, func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+
+		if r.Header.Get("Content-Encoding") != "" {
+			t.Error("expected Content-Encoding to be removed")
+		}
+	})
+
+	if string(body) != `{"name":"ada"}` {
+		t.Error("unexpected decompressed body: ", string(body))
+	}
+}
+
+func TestDecompressBomb(t *testing.T) {
+
+	req, _ := http.NewRequest("POST", "/", gzipBody(make([]byte, 10<<20)))
+	req.Header.Set("Content-Encoding", "gzip")
+
+	var err error
+
+	Decompress(WithMaxRatio(10))(httptest.NewRecorder(), req, func(w http.ResponseWriter, r *http.Request) {
+		_, err = io.ReadAll(r.Body)
+	})
+
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		t.Fatal("expected decompression to stop with MaxBytesError, got ", err)
+	}
+}
+
+func TestDecompressUnsupported(t *testing.T) {
+
+	req, _ := http.NewRequest("POST", "/", strings.NewReader("data"))
+	req.Header.Set("Content-Encoding", "br")
+	w := httptest.NewRecorder()
+
+	Decompress()(w, req, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler should not be called")
+	})
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Error("expected 415, got ", w.Code)
+	}
+}
@@ -0,0 +1,822 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+type CacheOption func(*CacheConfig)
+
+type CacheConfig struct {
+	Store             *ResponseCache
+	TTL               time.Duration
+	QueryKeys         []string
+	MaxEntrySize      int
+	CredentialHeaders []string
+}
+
+// WithCacheStore sets the cache that responses are stored in. Share a store
+// between several Cache middleware to invalidate their entries together.
+func WithCacheStore(store *ResponseCache) CacheOption {
+	return func(c *CacheConfig) {
+		c.Store = store
+	}
+}
+
+// WithCacheTTL sets how long responses without a max-age or s-maxage
+// Cache-Control directive are fresh. By default such responses are not
+// cached.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *CacheConfig) {
+		c.TTL = ttl
+	}
+}
+
+// WithCacheQueryKeys limits the query parameters that are part of the cache
+// key to keys. By default the whole query is part of the key.
+func WithCacheQueryKeys(keys ...string) CacheOption {
+	return func(c *CacheConfig) {
+		c.QueryKeys = keys
+	}
+}
+
+// WithMaxEntrySize sets the largest response body that is cached.
+func WithMaxEntrySize(n int) CacheOption {
+	return func(c *CacheConfig) {
+		c.MaxEntrySize = n
+	}
+}
+
+// WithCredentialHeaders adds request headers that carry credentials, such as
+// a custom API key header. Authorization, Cookie and X-API-Key are
+// credentials by default.
+func WithCredentialHeaders(headers ...string) CacheOption {
+	return func(c *CacheConfig) {
+		c.CredentialHeaders = append(c.CredentialHeaders, headers...)
+	}
+}
+
+// Cache serves GET requests from an in-memory LRU cache. Responses are keyed
+// by the matched route pattern, its params, the query and the request
+// headers named in the response's Vary header.
+//
+// Only 200 responses are stored, and only if their Cache-Control allows a
+// shared cache to store them and gives them a lifetime, either through
+// max-age or s-maxage or through WithCacheTTL. The stale-while-revalidate
+// and stale-if-error directives are honoured. Concurrent misses for the same
+// key wait for a single handler call. Handlers attach tags to a response with
+// CacheTag and drop tagged entries with InvalidateCache.
+//
+// Requests with credentials, that is a credential header or an authenticated
+// router.Principal, are only served from and stored in the cache if the
+// response is marked public or has s-maxage. Otherwise they always reach the
+// handler, so one client's response is never served to another. Routes with
+// requirements declared with Route.Require are not cached at all, as a hit
+// would bypass the router's Policy.
+func Cache(opts ...CacheOption) router.Middleware {
+
+	config := &CacheConfig{
+		MaxEntrySize:      1 << 20, // 1 MiB
+		CredentialHeaders: []string{"Authorization", "Cookie", "X-API-Key"},
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.Store == nil {
+		config.Store = NewResponseCache(64 << 20) // 64 MiB
+	}
+
+	c := config.Store
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+
+		r = cacheStateKey.Set(r, &cacheState{cache: c})
+
+		if r.Method != http.MethodGet || len(router.RouteRequirements(r)) > 0 {
+			next(w, r)
+			return
+		}
+
+		base := cacheKey(r, config.QueryKeys)
+		credentialed := hasCredentials(r, config.CredentialHeaders)
+		now := time.Now()
+
+		c.mu.Lock()
+
+		key := c.lookupKey(base, r)
+		stale := c.get(key)
+
+		if stale != nil && credentialed && !stale.shared {
+			stale = nil
+		}
+
+		if stale != nil {
+
+			age := now.Sub(stale.stored)
+
+			if age <= stale.maxAge {
+				c.mu.Unlock()
+				stale.serve(w, "HIT", now)
+				return
+			}
+
+			if age <= stale.maxAge+stale.staleWhileRevalidate {
+
+				if _, ok := c.calls[key]; !ok {
+					call := &cacheCall{done: make(chan struct{})}
+					c.calls[key] = call
+
+					bg := r.Clone(context.WithoutCancel(r.Context()))
+					go func() {
+						defer func() {
+							if v := recover(); v != nil {
+								router.Log(bg).Error("cache revalidation panicked", "panic", v)
+							}
+						}()
+
+						c.fill(nil, bg, next, base, key, call, stale, config)
+					}()
+				}
+
+				c.mu.Unlock()
+				stale.serve(w, "STALE", now)
+				return
+			}
+
+			if age > stale.maxAge+stale.staleIfError {
+				stale = nil
+			}
+		}
+
+		if credentialed {
+			// Do not wait for or share a call with other clients, as the
+			// response may be specific to these credentials.
+			c.mu.Unlock()
+			c.fill(w, r, next, base, key, &cacheCall{done: make(chan struct{})}, stale, config)
+			return
+		}
+
+		if call, ok := c.calls[key]; ok {
+			c.mu.Unlock()
+
+			select {
+			case <-call.done:
+			case <-r.Context().Done():
+				return
+			}
+
+			if call.entry != nil {
+				call.entry.serve(w, "HIT", time.Now())
+				return
+			}
+
+			next(w, r)
+			return
+		}
+
+		call := &cacheCall{done: make(chan struct{})}
+		c.calls[key] = call
+
+		c.mu.Unlock()
+
+		c.fill(w, r, next, base, key, call, stale, config)
+	}
+}
+
+// CacheTag tags the response being generated for r, so that it can be
+// dropped with InvalidateCache.
+func CacheTag(r *http.Request, tags ...string) {
+
+	s, ok := cacheStateKey.Get(r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	s.tags = append(s.tags, tags...)
+	s.mu.Unlock()
+}
+
+// InvalidateCache drops all responses tagged with any of tags from the cache
+// of the Cache middleware serving r.
+func InvalidateCache(r *http.Request, tags ...string) {
+
+	if s, ok := cacheStateKey.Get(r); ok {
+		s.cache.Invalidate(tags...)
+	}
+}
+
+var cacheStateKey = router.NewKey[*cacheState]("cache")
+
+type cacheState struct {
+	cache *ResponseCache
+	mu    sync.Mutex
+	tags  []string
+}
+
+type cacheCall struct {
+	done  chan struct{}
+	entry *cacheEntry
+}
+
+type cacheEntry struct {
+	base                 string
+	key                  string
+	status               int
+	header               http.Header
+	body                 []byte
+	tags                 []string
+	stored               time.Time
+	maxAge               time.Duration
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+
+	// shared is set for responses marked public or with s-maxage, which
+	// may also be served to requests with credentials.
+	shared bool
+}
+
+func (e *cacheEntry) size() int64 {
+
+	n := len(e.key) + len(e.body)
+
+	for k, v := range e.header {
+		n += len(k)
+		for _, s := range v {
+			n += len(s)
+		}
+	}
+
+	return int64(n)
+}
+
+func (e *cacheEntry) serve(w http.ResponseWriter, status string, now time.Time) {
+
+	h := w.Header()
+	for k, v := range e.header {
+		h[k] = append([]string(nil), v...)
+	}
+
+	h.Set("Age", strconv.Itoa(int(now.Sub(e.stored).Seconds())))
+	h.Set("X-Cache", status)
+	h.Set("Content-Length", strconv.Itoa(len(e.body)))
+
+	w.WriteHeader(e.status)
+	_, _ = w.Write(e.body)
+}
+
+// ResponseCache is a size bounded LRU cache of responses used by Cache.
+type ResponseCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	lru      *list.List
+	entries  map[string]*list.Element
+	varies   map[string]*cacheVary
+	tags     map[string]map[string]struct{}
+	calls    map[string]*cacheCall
+}
+
+// NewResponseCache returns a cache holding up to maxBytes of responses.
+func NewResponseCache(maxBytes int64) *ResponseCache {
+	return &ResponseCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		varies:   make(map[string]*cacheVary),
+		tags:     make(map[string]map[string]struct{}),
+		calls:    make(map[string]*cacheCall),
+	}
+}
+
+// Invalidate drops all responses tagged with any of tags.
+func (c *ResponseCache) Invalidate(tags ...string) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if el, ok := c.entries[key]; ok {
+				c.remove(el)
+			}
+		}
+	}
+}
+
+// Len returns the number of cached responses.
+func (c *ResponseCache) Len() int {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// cacheVary holds the Vary header names of the last response stored for a
+// resource, and how many entries for the resource are cached.
+type cacheVary struct {
+	names   []string
+	entries int
+}
+
+// lookupKey extends base with the values of the request headers named by
+// the Vary header of the last response stored for base.
+func (c *ResponseCache) lookupKey(base string, r *http.Request) string {
+
+	vary, ok := c.varies[base]
+	if !ok {
+		return base
+	}
+
+	return varyKey(base, vary.names, r)
+}
+
+func varyKey(base string, names []string, r *http.Request) string {
+
+	if len(names) == 0 {
+		return base
+	}
+
+	var sb strings.Builder
+	sb.WriteString(base)
+
+	for _, name := range names {
+		sb.WriteString("\x00")
+		sb.WriteString(name)
+		sb.WriteString("=")
+		sb.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+
+	return sb.String()
+}
+
+func (c *ResponseCache) get(key string) *cacheEntry {
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	c.lru.MoveToFront(el)
+
+	return el.Value.(*cacheEntry)
+}
+
+func (c *ResponseCache) add(base string, r *http.Request, e *cacheEntry) {
+
+	names := varyHeaders(e.header)
+
+	e.base = base
+	e.key = varyKey(base, names, r)
+
+	if el, ok := c.entries[e.key]; ok {
+		c.remove(el)
+	}
+
+	size := e.size()
+	if size > c.maxBytes {
+		return
+	}
+
+	vary, ok := c.varies[base]
+	if !ok {
+		vary = &cacheVary{}
+		c.varies[base] = vary
+	}
+
+	vary.names = names
+	vary.entries++
+
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.size += size
+
+	for _, tag := range e.tags {
+
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			c.tags[tag] = keys
+		}
+
+		keys[e.key] = struct{}{}
+	}
+
+	for c.size > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *ResponseCache) remove(el *list.Element) {
+
+	e := c.lru.Remove(el).(*cacheEntry)
+	delete(c.entries, e.key)
+	c.size -= e.size()
+
+	if vary := c.varies[e.base]; vary != nil {
+		if vary.entries--; vary.entries == 0 {
+			delete(c.varies, e.base)
+		}
+	}
+
+	for _, tag := range e.tags {
+
+		keys := c.tags[tag]
+		delete(keys, e.key)
+
+		if len(keys) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+// fill calls the handler for a miss and stores the response if it may be
+// cached. If w is nil the response is only stored, which is how stale
+// entries are revalidated in the background. stale, if not nil, is served
+// instead of a 5xx response.
+func (c *ResponseCache) fill(w http.ResponseWriter, r *http.Request, next http.HandlerFunc, base, key string, call *cacheCall, stale *cacheEntry, config *CacheConfig) {
+
+	state := &cacheState{cache: c}
+	r = cacheStateKey.Set(r, state)
+
+	cw := &cacheWriter{
+		w:      w,
+		header: make(http.Header),
+		status: http.StatusOK,
+		max:    config.MaxEntrySize,
+	}
+
+	defer func() {
+		c.mu.Lock()
+		if c.calls[key] == call {
+			delete(c.calls, key)
+		}
+		c.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	next(cw, r)
+
+	if cw.passthrough {
+		return
+	}
+
+	now := time.Now()
+
+	if cw.status >= 500 && stale != nil {
+		call.entry = stale
+
+		if w != nil {
+			stale.serve(w, "STALE", now)
+		}
+
+		return
+	}
+
+	state.mu.Lock()
+	tags := state.tags
+	state.mu.Unlock()
+
+	e := newCacheEntry(cw, tags, now, config.TTL, hasCredentials(r, config.CredentialHeaders))
+
+	if e != nil {
+		c.mu.Lock()
+		c.add(base, r, e)
+		c.mu.Unlock()
+
+		call.entry = e
+	}
+
+	if w == nil {
+		return
+	}
+
+	h := w.Header()
+	for k, v := range cw.header {
+		h[k] = v
+	}
+
+	h.Set("X-Cache", "MISS")
+	h.Set("Content-Length", strconv.Itoa(cw.buf.Len()))
+
+	w.WriteHeader(cw.status)
+	_, _ = w.Write(cw.buf.Bytes())
+}
+
+// newCacheEntry returns an entry for the buffered response, or nil if it may
+// not be stored by a shared cache.
+func newCacheEntry(cw *cacheWriter, tags []string, now time.Time, ttl time.Duration, credentialed bool) *cacheEntry {
+
+	if cw.status != http.StatusOK {
+		return nil
+	}
+
+	h := cw.header
+
+	if h.Get("Set-Cookie") != "" {
+		return nil
+	}
+
+	for _, name := range varyHeaders(h) {
+		if name == "*" {
+			return nil
+		}
+	}
+
+	cc := parseCacheControl(h.Get("Cache-Control"))
+
+	if _, ok := cc["no-store"]; ok {
+		return nil
+	}
+
+	if _, ok := cc["private"]; ok {
+		return nil
+	}
+
+	if _, ok := cc["no-cache"]; ok {
+		return nil
+	}
+
+	_, public := cc["public"]
+	_, shared := cc["s-maxage"]
+
+	if credentialed && !public && !shared {
+		return nil
+	}
+
+	maxAge := ttl
+	if v, ok := cc["s-maxage"]; ok {
+		maxAge = parseSeconds(v)
+	} else if v, ok := cc["max-age"]; ok {
+		maxAge = parseSeconds(v)
+	}
+
+	if maxAge <= 0 {
+		return nil
+	}
+
+	return &cacheEntry{
+		status:               cw.status,
+		header:               h.Clone(),
+		body:                 bytes.Clone(cw.buf.Bytes()),
+		tags:                 tags,
+		stored:               now,
+		maxAge:               maxAge,
+		staleWhileRevalidate: parseSeconds(cc["stale-while-revalidate"]),
+		staleIfError:         parseSeconds(cc["stale-if-error"]),
+		shared:               public || shared,
+	}
+}
+
+// hasCredentials reports whether r carries any of the credential headers or
+// is authenticated.
+func hasCredentials(r *http.Request, headers []string) bool {
+
+	if router.Principal(r) != nil {
+		return true
+	}
+
+	for _, name := range headers {
+		if r.Header.Get(name) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cacheKey identifies the resource requested by r, ignoring the request
+// headers named by Vary.
+func cacheKey(r *http.Request, queryKeys []string) string {
+
+	var sb strings.Builder
+
+	pattern := router.RoutePattern(r)
+	if pattern == "" {
+		pattern = r.URL.Path
+	}
+
+	sb.WriteString(r.Host)
+	sb.WriteString("\x00")
+	sb.WriteString(router.MountPrefix(r))
+	sb.WriteString(pattern)
+
+	params := router.RouteParams(r)
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		sb.WriteString("\x00")
+		sb.WriteString(name)
+		sb.WriteString("=")
+		sb.WriteString(params[name])
+	}
+
+	query := r.URL.Query()
+
+	if queryKeys != nil {
+		selected := url.Values{}
+		for _, k := range queryKeys {
+			if v, ok := query[k]; ok {
+				selected[k] = v
+			}
+		}
+
+		query = selected
+	}
+
+	sb.WriteString("\x00")
+	sb.WriteString(query.Encode())
+
+	return sb.String()
+}
+
+func varyHeaders(h http.Header) []string {
+
+	var names []string
+
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func parseCacheControl(v string) map[string]string {
+
+	cc := make(map[string]string)
+
+	for _, directive := range strings.Split(v, ",") {
+
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(directive, "=")
+		cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+
+	return cc
+}
+
+func parseSeconds(v string) time.Duration {
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0
+	}
+
+	return time.Duration(n) * time.Second
+}
+
+// cacheWriter buffers a response for the cache. Flushed, hijacked or too
+// large responses are passed through to w, or discarded if w is nil, and
+// are not cached.
+type cacheWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	status      int
+	buf         bytes.Buffer
+	max         int
+	passthrough bool
+}
+
+func (w *cacheWriter) Header() http.Header {
+
+	if w.passthrough && w.w != nil {
+		return w.w.Header()
+	}
+
+	return w.header
+}
+
+func (w *cacheWriter) WriteHeader(code int) {
+
+	if w.passthrough {
+		if w.w != nil {
+			w.w.WriteHeader(code)
+		}
+		return
+	}
+
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		return
+	}
+
+	w.status = code
+
+	if strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream") {
+		_ = w.startPassthrough()
+	}
+}
+
+func (w *cacheWriter) Write(b []byte) (int, error) {
+
+	if w.passthrough {
+		if w.w == nil {
+			return len(b), nil
+		}
+		return w.w.Write(b)
+	}
+
+	if w.buf.Len()+len(b) > w.max {
+		if err := w.startPassthrough(); err != nil {
+			return 0, err
+		}
+
+		return w.Write(b)
+	}
+
+	return w.buf.Write(b)
+}
+
+func (w *cacheWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *cacheWriter) FlushError() error {
+
+	if err := w.startPassthrough(); err != nil {
+		return err
+	}
+
+	if w.w == nil {
+		return nil
+	}
+
+	return http.NewResponseController(w.w).Flush()
+}
+
+func (w *cacheWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+
+	if w.w == nil {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, brw, err := http.NewResponseController(w.w).Hijack()
+	if err == nil {
+		w.passthrough = true
+	}
+
+	return conn, brw, err
+}
+
+func (w *cacheWriter) Unwrap() http.ResponseWriter {
+	return w.w
+}
+
+func (w *cacheWriter) startPassthrough() error {
+
+	if w.passthrough {
+		return nil
+	}
+
+	w.passthrough = true
+
+	if w.w == nil {
+		return nil
+	}
+
+	h := w.w.Header()
+	for k, v := range w.header {
+		h[k] = v
+	}
+
+	w.w.WriteHeader(w.status)
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+
+	_, err := w.w.Write(w.buf.Bytes())
+	w.buf = bytes.Buffer{}
+
+	return err
+}
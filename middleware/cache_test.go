@@ -0,0 +1,328 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+func cacheGet(t *testing.T, h http.Handler, path string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	return w
+}
+
+// expire moves the stored time of all entries back by d.
+func expire(c *ResponseCache, d time.Duration) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		el.Value.(*cacheEntry).stored = el.Value.(*cacheEntry).stored.Add(-d)
+	}
+}
+
+func TestCache(t *testing.T) {
+
+	store := NewResponseCache(1 << 20)
+
+	var calls atomic.Int32
+
+	r := router.New()
+	r.Use(Cache(WithCacheStore(store), WithCacheQueryKeys("page")))
+
+	r.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		CacheTag(r, "user:"+router.RouteParam(r, "id"))
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte(strconv.Itoa(int(n))))
+	})
+
+	r.Get("/private", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "private, max-age=60")
+	})
+
+	r.Post("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		InvalidateCache(r, "user:"+router.RouteParam(r, "id"))
+	})
+
+	tests := []struct {
+		path   string
+		xcache string
+		body   string
+	}{
+		{"/users/1", "MISS", "1"},
+		{"/users/1", "HIT", "1"},
+		{"/users/1?page=2", "MISS", "2"},
+		{"/users/1?page=2&sort=name", "HIT", "2"},
+		{"/users/2", "MISS", "3"},
+	}
+
+	for _, tt := range tests {
+		w := cacheGet(t, r, tt.path)
+		if w.Header().Get("X-Cache") != tt.xcache || w.Body.String() != tt.body {
+			t.Errorf("%s: expected %s %s, got %s %s", tt.path, tt.xcache, tt.body, w.Header().Get("X-Cache"), w.Body.String())
+		}
+	}
+
+	cacheGet(t, r, "/private")
+	cacheGet(t, r, "/private")
+
+	if calls.Load() != 5 {
+		t.Error("expected private responses not to be cached, calls: ", calls.Load())
+	}
+
+	req, _ := http.NewRequest("POST", "/users/1", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if store.Len() != 1 {
+		t.Fatal("expected tagged entries to be invalidated, entries: ", store.Len())
+	}
+
+	if w := cacheGet(t, r, "/users/1"); w.Header().Get("X-Cache") != "MISS" {
+		t.Error("expected miss after invalidation")
+	}
+}
+
+func TestCacheCredentials(t *testing.T) {
+
+	r := router.New()
+	r.Use(Cache())
+
+	r.Get("/me", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("user:" + r.Header.Get("X-API-Key")))
+	})
+
+	r.Get("/catalog", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		_, _ = w.Write([]byte("catalog"))
+	})
+
+	tests := []struct {
+		path   string
+		header []string
+		xcache string
+		body   string
+	}{
+		{"/me", []string{"X-API-Key", "alice"}, "MISS", "user:alice"},
+		{"/me", nil, "MISS", "user:"},
+		{"/me", []string{"X-API-Key", "bob"}, "MISS", "user:bob"},
+		{"/me", []string{"Cookie", "session=bob"}, "MISS", "user:"},
+		{"/me", nil, "HIT", "user:"},
+		{"/catalog", []string{"X-API-Key", "alice"}, "MISS", "catalog"},
+		{"/catalog", nil, "HIT", "catalog"},
+		{"/catalog", []string{"Authorization", "Bearer bob"}, "HIT", "catalog"},
+	}
+
+	for i, tt := range tests {
+		w := cacheGet(t, r, tt.path, tt.header...)
+		if w.Header().Get("X-Cache") != tt.xcache || w.Body.String() != tt.body {
+			t.Errorf("request %d %s %v: expected %s %s, got %s %s", i, tt.path, tt.header, tt.xcache, tt.body, w.Header().Get("X-Cache"), w.Body.String())
+		}
+	}
+}
+
+func TestCacheAuthenticatedPrincipal(t *testing.T) {
+
+	r := router.New()
+
+	r.Use(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if user := r.URL.Query().Get("as"); user != "" {
+			r = router.SetPrincipal(r, &router.Identity{Subject: user})
+		}
+		next(w, r)
+	})
+	r.Use(Cache(WithCacheQueryKeys()))
+
+	r.Get("/me", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		if id := router.Principal(r); id != nil {
+			_, _ = w.Write([]byte(id.Subject))
+		}
+	})
+
+	cacheGet(t, r, "/me?as=alice")
+
+	if w := cacheGet(t, r, "/me"); w.Header().Get("X-Cache") != "MISS" || w.Body.String() != "" {
+		t.Error("expected the response for alice not to be served to anonymous requests, got ", w.Body.String())
+	}
+}
+
+func TestCacheSkipsRoutesWithRequirements(t *testing.T) {
+
+	r := router.New()
+
+	r.Use(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if r.Header.Get("X-Admin") != "" {
+			r = router.SetPrincipal(r, &router.Identity{Subject: "admin", Roles: []string{"admin"}})
+		}
+		next(w, r)
+	}, Cache())
+
+	r.Get("/reports", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		_, _ = w.Write([]byte("secret report"))
+	}).Require("role:admin")
+
+	if w := cacheGet(t, r, "/reports", "X-Admin", "1"); w.Code != http.StatusOK {
+		t.Fatal("expected 200 for the admin, got ", w.Code)
+	}
+
+	w := cacheGet(t, r, "/reports")
+
+	if w.Code != http.StatusUnauthorized || w.Header().Get("X-Cache") != "" {
+		t.Errorf("expected 401 without a cache hit, got %d %q", w.Code, w.Header().Get("X-Cache"))
+	}
+}
+
+func TestCacheVary(t *testing.T) {
+
+	r := router.New()
+	r.Use(Cache())
+
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		_, _ = w.Write([]byte(r.Header.Get("Accept-Language")))
+	})
+
+	for _, lang := range []string{"en", "de", "en", "de"} {
+		if w := cacheGet(t, r, "/", "Accept-Language", lang); w.Body.String() != lang {
+			t.Errorf("expected %s, got %s", lang, w.Body.String())
+		}
+	}
+}
+
+func TestCacheCollapsesMisses(t *testing.T) {
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	mw := Cache()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mw(w, r, func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			<-release
+			w.Header().Set("Cache-Control", "max-age=60")
+			_, _ = w.Write([]byte("ok"))
+		})
+	})
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 10)
+
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i] = cacheGet(t, handler, "/").Body.String()
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Error("expected a single handler call, got ", calls.Load())
+	}
+
+	for _, body := range bodies {
+		if body != "ok" {
+			t.Error("expected every request to get the response, got ", body)
+		}
+	}
+}
+
+func TestCacheStale(t *testing.T) {
+
+	store := NewResponseCache(1 << 20)
+
+	var calls atomic.Int32
+	var fail atomic.Bool
+	revalidated := make(chan struct{}, 1)
+
+	r := router.New()
+	r.Use(Cache(WithCacheStore(store)))
+
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		defer func() { revalidated <- struct{}{} }()
+
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		n := calls.Add(1)
+		w.Header().Set("Cache-Control", "max-age=10, stale-while-revalidate=10, stale-if-error=60")
+		_, _ = w.Write([]byte(strconv.Itoa(int(n))))
+	})
+
+	cacheGet(t, r, "/")
+	<-revalidated
+
+	expire(store, 15*time.Second)
+
+	if w := cacheGet(t, r, "/"); w.Header().Get("X-Cache") != "STALE" || w.Body.String() != "1" {
+		t.Errorf("expected stale response, got %s %s", w.Header().Get("X-Cache"), w.Body.String())
+	}
+
+	<-revalidated
+
+	if w := cacheGet(t, r, "/"); w.Header().Get("X-Cache") != "HIT" || w.Body.String() != "2" {
+		t.Errorf("expected revalidated response, got %s %s", w.Header().Get("X-Cache"), w.Body.String())
+	}
+
+	fail.Store(true)
+	expire(store, 30*time.Second)
+
+	if w := cacheGet(t, r, "/"); w.Code != http.StatusOK || w.Body.String() != "2" {
+		t.Errorf("expected stale response on error, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+
+	store := NewResponseCache(300)
+
+	r := router.New()
+	r.Use(Cache(WithCacheStore(store), WithCacheTTL(time.Minute)))
+
+	r.Get("/:id", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, 100))
+	})
+
+	for _, path := range []string{"/1", "/2", "/1", "/3"} {
+		cacheGet(t, r, path)
+	}
+
+	if store.Len() != 2 {
+		t.Fatal("expected 2 entries, got ", store.Len())
+	}
+
+	if w := cacheGet(t, r, "/1"); w.Header().Get("X-Cache") != "HIT" {
+		t.Error("expected recently used entry to be kept")
+	}
+
+	if w := cacheGet(t, r, "/2"); w.Header().Get("X-Cache") != "MISS" {
+		t.Error("expected least recently used entry to be evicted")
+	}
+}
@@ -0,0 +1,343 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+type CompressOption func(*CompressConfig)
+
+type CompressConfig struct {
+	Level        int
+	MinSize      int
+	ContentTypes []string
+}
+
+func WithCompressLevel(level int) CompressOption {
+	return func(c *CompressConfig) {
+		c.Level = level
+	}
+}
+
+// WithMinSize leaves responses smaller than n bytes uncompressed.
+func WithMinSize(n int) CompressOption {
+	return func(c *CompressConfig) {
+		c.MinSize = n
+	}
+}
+
+// WithContentTypes sets the media types that are compressed. A trailing
+// "/*" matches any subtype.
+func WithContentTypes(types ...string) CompressOption {
+	return func(c *CompressConfig) {
+		c.ContentTypes = types
+	}
+}
+
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Compress compresses responses with gzip or deflate, as negotiated from
+// the Accept-Encoding header. Responses that are small, already encoded,
+// partial (206) or not of an allowed content type are sent as they are.
+// Requests with a Range header are never compressed so that byte ranges
+// always refer to the identity representation. It panics if the level set
+// with WithCompressLevel is not a valid gzip level.
+func Compress(opts ...CompressOption) router.Middleware {
+
+	config := &CompressConfig{
+		Level:   gzip.DefaultCompression,
+		MinSize: 1024,
+		ContentTypes: []string{
+			"text/*",
+			"application/json",
+			"application/problem+json",
+			"application/x-ndjson",
+			"application/javascript",
+			"application/xml",
+			"image/svg+xml",
+		},
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.Level < gzip.HuffmanOnly || config.Level > gzip.BestCompression {
+		panic(fmt.Sprintf("middleware: invalid compression level %d, want %d to %d", config.Level, gzip.HuffmanOnly, gzip.BestCompression))
+	}
+
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(io.Discard, config.Level)
+			return w
+		}},
+		"deflate": {New: func() interface{} {
+			w, _ := zlib.NewWriterLevel(io.Discard, config.Level)
+			return w
+		}},
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+
+		if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+			next(w, r)
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: w,
+			config:         config,
+			encoding:       encoding,
+			pool:           pools[encoding],
+			status:         http.StatusOK,
+		}
+
+		defer cw.close()
+
+		next(cw, r)
+	}
+}
+
+// negotiateEncoding returns "gzip", "deflate" or "" for the given
+// Accept-Encoding header value. gzip is preferred when both are equally
+// acceptable.
+func negotiateEncoding(accept string) string {
+
+	q := map[string]float64{}
+	wildcard := -1.0
+
+	for _, part := range strings.Split(accept, ",") {
+
+		token, params, _ := strings.Cut(part, ";")
+		token = strings.ToLower(strings.TrimSpace(token))
+
+		weight := 1.0
+
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				weight = v
+			}
+		}
+
+		if token == "*" {
+			wildcard = weight
+		} else if token != "" {
+			q[token] = weight
+		}
+	}
+
+	weight := func(encoding string) float64 {
+		if v, ok := q[encoding]; ok {
+			return v
+		}
+		if wildcard >= 0 {
+			return wildcard
+		}
+		return 0
+	}
+
+	gz, df := weight("gzip"), weight("deflate")
+
+	switch {
+	case gz > 0 && gz >= df:
+		return "gzip"
+	case df > 0:
+		return "deflate"
+	}
+
+	return ""
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	config   *CompressConfig
+	encoding string
+	pool     *sync.Pool
+
+	status   int
+	buf      []byte
+	decided  bool
+	compress bool
+	cw       compressor
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+
+	if w.decided {
+		return
+	}
+
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	w.status = code
+
+	if !bodyAllowed(code) {
+		w.decide(true)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+
+		if len(w.buf) < w.config.MinSize {
+			return len(b), nil
+		}
+
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+
+		return len(b), nil
+	}
+
+	if w.compress {
+		return w.cw.Write(b)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError decides on compression with whatever has been written so far,
+// since a flushing handler is streaming and its final size is unknown.
+func (w *compressWriter) FlushError() error {
+
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return err
+		}
+	}
+
+	if w.compress {
+		if err := w.cw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide chooses whether to compress, writes the header and any buffered
+// data. bigEnough is false when the response completed below MinSize.
+func (w *compressWriter) decide(bigEnough bool) error {
+
+	w.decided = true
+
+	h := w.Header()
+
+	if ct := h.Get("Content-Type"); ct == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	w.compress = bigEnough &&
+		bodyAllowed(w.status) &&
+		w.status != http.StatusPartialContent &&
+		h.Get("Content-Encoding") == "" &&
+		h.Get("Content-Range") == "" &&
+		w.allowedType(h.Get("Content-Type"))
+
+	if w.compress {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+
+		// The compressed representation is not byte-identical to the one a
+		// strong ETag was computed for.
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			h.Set("ETag", "W/"+etag)
+		}
+
+		w.cw = w.pool.Get().(compressor)
+		w.cw.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	buf := w.buf
+	w.buf = nil
+
+	var err error
+	if w.compress {
+		_, err = w.cw.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+
+	return err
+}
+
+func (w *compressWriter) close() {
+
+	if !w.decided {
+		// Nothing was written at all: let net/http write the header.
+		if len(w.buf) == 0 && w.status == http.StatusOK {
+			return
+		}
+
+		_ = w.decide(false)
+	}
+
+	if w.compress {
+		_ = w.cw.Close()
+		w.cw.Reset(io.Discard)
+		w.pool.Put(w.cw)
+		w.cw = nil
+	}
+}
+
+func (w *compressWriter) allowedType(contentType string) bool {
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, t := range w.config.ContentTypes {
+		if strings.HasSuffix(t, "/*") {
+			if strings.HasPrefix(mediaType, t[:len(t)-1]) {
+				return true
+			}
+		} else if t == mediaType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
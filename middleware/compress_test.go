@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+
+	tests := map[string]string{
+		"":                          "",
+		"gzip":                      "gzip",
+		"deflate":                   "deflate",
+		"gzip, deflate, br":         "gzip",
+		"gzip;q=0.5, deflate":       "deflate",
+		"*":                         "gzip",
+		"*;q=0.5, gzip;q=0":         "deflate",
+		"identity, gzip;q=0":        "",
+		"br":                        "",
+		"GZIP;q=0.8, deflate;q=0.2": "gzip",
+	}
+
+	for accept, expected := range tests {
+		if got := negotiateEncoding(accept); got != expected {
+			t.Errorf("negotiateEncoding(%q) = %q, expected %q", accept, got, expected)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+
+	body := strings.Repeat(`{"name":"ada"},`, 200)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	Compress()(w, req, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "3000")
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(body))
+	})
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("expected gzip encoding")
+	}
+
+	if w.Header().Get("Content-Length") != "" || w.Header().Get("Vary") != "Accept-Encoding" || w.Header().Get("ETag") != `W/"v1"` {
+		t.Error("unexpected headers: ", w.Header())
+	}
+
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, _ := io.ReadAll(gr)
+	if string(decoded) != body {
+		t.Error("decompressed body does not match")
+	}
+}
+
+func TestCompressSkips(t *testing.T) {
+
+	large := strings.Repeat("a", 2000)
+
+	tests := []struct {
+		name        string
+		contentType string
+		encoding    string
+		body        string
+	}{
+		{"small", "text/plain", "", "hello"},
+		{"image", "image/png", "", large},
+		{"encoded", "text/plain", "br", large},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+
+		Compress()(w, req, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tt.contentType)
+			if tt.encoding != "" {
+				w.Header().Set("Content-Encoding", tt.encoding)
+			}
+			_, _ = w.Write([]byte(tt.body))
+		})
+
+		if w.Header().Get("Content-Encoding") != tt.encoding || w.Body.String() != tt.body {
+			t.Errorf("%s: expected response to be left alone", tt.name)
+		}
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	Compress()(w, req, func(w http.ResponseWriter, r *http.Request) {
+
+		stream, err := router.SSE(w, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_ = stream.Send(router.Event{Data: "hello"})
+
+		if !w.(*compressWriter).decided || w.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatal("expected flush to start a compressed response")
+		}
+
+		// Everything sent so far must be decodable before the stream ends.
+		gr, err := gzip.NewReader(strings.NewReader(w.(*compressWriter).ResponseWriter.(*httptest.ResponseRecorder).Body.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf := make([]byte, 64)
+		n, _ := io.ReadAtLeast(gr, buf, len("data: hello\n\n"))
+
+		if string(buf[:n]) != "data: hello\n\n" {
+			t.Errorf("unexpected flushed data %q", buf[:n])
+		}
+	})
+}
+
+func TestCompressRange(t *testing.T) {
+
+	r := router.New()
+	r.Use(Compress(WithMinSize(1)))
+	r.Static("/", "../testdata")
+
+	req, _ := http.NewRequest("GET", "/test.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-4")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPartialContent || w.Body.String() != "hello" || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected an uncompressed partial response, got %d %q", w.Code, w.Body.String())
+	}
+
+	req.Header.Del("Range")
+	w = httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Accept-Ranges") != "" {
+		t.Error("expected a compressed full response without Accept-Ranges")
+	}
+}
+
+func TestCompressInvalidLevel(t *testing.T) {
+
+	for _, level := range []int{-3, 10} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected Compress with level %d to panic", level)
+				}
+			}()
+
+			Compress(WithCompressLevel(level))
+		}()
+	}
+}
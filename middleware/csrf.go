@@ -0,0 +1,281 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+var ErrCSRF = router.NewHTTPError(http.StatusForbidden, "csrf_failed", "CSRF validation failed")
+
+const csrfTokenSize = 32
+
+type CSRFOption func(*CSRFConfig)
+
+type CSRFConfig struct {
+	CookieName     string
+	HeaderName     string
+	FieldName      string
+	SecureCookie   bool
+	TrustedOrigins []string
+	Exempt         []string
+	Session        func(r *http.Request) string
+}
+
+func WithCSRFCookieName(name string) CSRFOption {
+	return func(c *CSRFConfig) {
+		c.CookieName = name
+	}
+}
+
+// WithCSRFHeader sets the request header checked for the token. The default
+// is X-CSRF-Token.
+func WithCSRFHeader(name string) CSRFOption {
+	return func(c *CSRFConfig) {
+		c.HeaderName = name
+	}
+}
+
+// WithCSRFField sets the form field checked for the token when the header is
+// not set. The default is csrf_token.
+func WithCSRFField(name string) CSRFOption {
+	return func(c *CSRFConfig) {
+		c.FieldName = name
+	}
+}
+
+// WithSecureCookie always marks the token cookie Secure. Otherwise it is
+// only marked Secure on TLS connections.
+func WithSecureCookie() CSRFOption {
+	return func(c *CSRFConfig) {
+		c.SecureCookie = true
+	}
+}
+
+// WithTrustedOrigins allows cross-origin requests from origins, such as
+// "https://admin.example.com".
+func WithTrustedOrigins(origins ...string) CSRFOption {
+	return func(c *CSRFConfig) {
+		c.TrustedOrigins = origins
+	}
+}
+
+// WithCSRFExempt skips the checks for the given route patterns, such as
+// "/webhooks/:provider".
+func WithCSRFExempt(patterns ...string) CSRFOption {
+	return func(c *CSRFConfig) {
+		c.Exempt = append(c.Exempt, patterns...)
+	}
+}
+
+// WithCSRFSession binds tokens to the session returned by fn, so a token
+// issued for one session is rejected in another.
+func WithCSRFSession(fn func(r *http.Request) string) CSRFOption {
+	return func(c *CSRFConfig) {
+		c.Session = fn
+	}
+}
+
+// CSRF protects against cross-site request forgery with signed double-submit
+// cookies. A random token signed with secret is kept in a cookie and exposed
+// to handlers with router.CSRFToken. Requests with unsafe methods must send
+// it back in the X-CSRF-Token header or the csrf_token form field.
+//
+// Before the token is checked, requests that Sec-Fetch-Site marks as
+// cross-site or same-site are rejected unless their Origin is trusted. If
+// the browser does not send Sec-Fetch-Site, the Origin, or the Referer on
+// TLS connections, must be the request host or a trusted origin. Safe
+// methods and exempt routes are not checked.
+func CSRF(secret []byte, opts ...CSRFOption) router.Middleware {
+
+	config := &CSRFConfig{
+		CookieName: "csrf",
+		HeaderName: "X-CSRF-Token",
+		FieldName:  "csrf_token",
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	sign := func(r *http.Request, token []byte) []byte {
+
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(token)
+
+		if config.Session != nil {
+			mac.Write([]byte(config.Session(r)))
+		}
+
+		return mac.Sum(nil)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+
+		w.Header().Add("Vary", "Cookie")
+
+		token := csrfCookieToken(r, config.CookieName, sign)
+
+		if token == nil {
+
+			token = make([]byte, csrfTokenSize)
+			if _, err := rand.Read(token); err != nil {
+				router.Error(w, r, err)
+				return
+			}
+
+			http.SetCookie(w, &http.Cookie{
+				Name:     config.CookieName,
+				Value:    base64.RawURLEncoding.EncodeToString(append(token, sign(r, token)...)),
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   config.SecureCookie || r.TLS != nil,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		r = router.SetCSRFToken(r, maskCSRFToken(token))
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			next(w, r)
+			return
+		}
+
+		if slices.Contains(config.Exempt, router.RoutePattern(r)) {
+			next(w, r)
+			return
+		}
+
+		if !csrfOriginAllowed(r, config.TrustedOrigins) {
+			router.Error(w, r, ErrCSRF)
+			return
+		}
+
+		sent := r.Header.Get(config.HeaderName)
+		if sent == "" {
+			sent = r.PostFormValue(config.FieldName)
+		}
+
+		if !csrfTokenMatches(sent, token) {
+			router.Error(w, r, ErrCSRF)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// csrfCookieToken returns the token in the cookie of r, or nil if there is
+// none or its signature is invalid.
+func csrfCookieToken(r *http.Request, name string, sign func(*http.Request, []byte) []byte) []byte {
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(b) != csrfTokenSize+sha256.Size {
+		return nil
+	}
+
+	token, sig := b[:csrfTokenSize], b[csrfTokenSize:]
+
+	if !hmac.Equal(sig, sign(r, token)) {
+		return nil
+	}
+
+	return token
+}
+
+// maskCSRFToken XORs token with a random pad so the value sent to the client
+// changes on every request, which defeats BREACH style compression attacks.
+func maskCSRFToken(token []byte) string {
+
+	masked := make([]byte, 2*len(token))
+	pad := masked[:len(token)]
+
+	_, _ = rand.Read(pad)
+
+	for i, b := range token {
+		masked[len(token)+i] = b ^ pad[i]
+	}
+
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+func csrfTokenMatches(sent string, token []byte) bool {
+
+	b, err := base64.RawURLEncoding.DecodeString(sent)
+	if err != nil || len(b) != 2*len(token) {
+		return false
+	}
+
+	pad, masked := b[:len(token)], b[len(token):]
+
+	unmasked := make([]byte, len(token))
+	for i := range unmasked {
+		unmasked[i] = masked[i] ^ pad[i]
+	}
+
+	return subtle.ConstantTimeCompare(unmasked, token) == 1
+}
+
+// csrfOriginAllowed reports whether r may come from a page of the request
+// host or a trusted origin. Sec-Fetch-Site is trusted when sent, since only
+// browsers set it; otherwise the scheme and host of Origin, or Referer on TLS
+// connections, are compared to those of the request. Behind a proxy that
+// terminates TLS the public origin must be trusted explicitly.
+func csrfOriginAllowed(r *http.Request, trusted []string) bool {
+
+	origin := r.Header.Get("Origin")
+
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "same-site", "cross-site":
+		u, err := url.Parse(origin)
+		return err == nil && slices.Contains(trusted, u.Scheme+"://"+u.Host)
+	}
+
+	if origin == "" && r.TLS != nil {
+		origin = r.Header.Get("Referer")
+
+		// Browsers send a Referer for same-origin requests over TLS unless
+		// a referrer policy suppresses it, so a missing one is suspicious.
+		if origin == "" {
+			return false
+		}
+	}
+
+	// Requests without Origin over plain HTTP come from older browsers or
+	// non-browser clients and are left to the token check.
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	if strings.EqualFold(u.Scheme, scheme) && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	return slices.Contains(trusted, u.Scheme+"://"+u.Host)
+}
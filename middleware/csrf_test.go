@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+func TestCSRF(t *testing.T) {
+
+	r := router.New()
+	r.Use(CSRF([]byte("secret"), WithTrustedOrigins("https://admin.example.com"), WithCSRFExempt("/webhooks/:provider")))
+
+	r.Get("/form", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(router.CSRFToken(r)))
+	})
+
+	r.Post("/form", func(w http.ResponseWriter, r *http.Request) {})
+	r.Post("/webhooks/:provider", func(w http.ResponseWriter, r *http.Request) {})
+
+	req, _ := http.NewRequest("GET", "http://example.com/form", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly {
+		t.Fatal("expected an HttpOnly token cookie")
+	}
+
+	cookie := cookies[0]
+	token := w.Body.String()
+
+	forged := &http.Cookie{Name: "csrf", Value: "A" + cookie.Value[1:]}
+	if cookie.Value[0] == 'A' {
+		forged.Value = "B" + cookie.Value[1:]
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		header map[string]string
+		form   string
+		cookie *http.Cookie
+		tls    bool
+		status int
+	}{
+		{"header token", "/form", map[string]string{"X-CSRF-Token": token}, "", cookie, false, http.StatusOK},
+		{"form token", "/form", nil, "csrf_token=" + url.QueryEscape(token), cookie, false, http.StatusOK},
+		{"missing token", "/form", nil, "", cookie, false, http.StatusForbidden},
+		{"missing cookie", "/form", map[string]string{"X-CSRF-Token": token}, "", nil, false, http.StatusForbidden},
+		{"forged cookie", "/form", map[string]string{"X-CSRF-Token": token}, "", forged, false, http.StatusForbidden},
+		{"cross-site", "/form", map[string]string{"X-CSRF-Token": token, "Sec-Fetch-Site": "cross-site", "Origin": "https://evil.com"}, "", cookie, false, http.StatusForbidden},
+		{"trusted origin", "/form", map[string]string{"X-CSRF-Token": token, "Sec-Fetch-Site": "same-site", "Origin": "https://admin.example.com"}, "", cookie, false, http.StatusOK},
+		{"foreign origin", "/form", map[string]string{"X-CSRF-Token": token, "Origin": "https://evil.com"}, "", cookie, false, http.StatusForbidden},
+		{"same origin", "/form", map[string]string{"X-CSRF-Token": token, "Origin": "http://example.com"}, "", cookie, false, http.StatusOK},
+		{"mismatched scheme", "/form", map[string]string{"X-CSRF-Token": token, "Origin": "https://example.com"}, "", cookie, false, http.StatusForbidden},
+		{"tls same origin", "/form", map[string]string{"X-CSRF-Token": token, "Origin": "https://example.com"}, "", cookie, true, http.StatusOK},
+		{"tls downgraded origin", "/form", map[string]string{"X-CSRF-Token": token, "Origin": "http://example.com"}, "", cookie, true, http.StatusForbidden},
+		{"tls referer", "/form", map[string]string{"X-CSRF-Token": token, "Referer": "https://example.com/form"}, "", cookie, true, http.StatusOK},
+		{"tls without referer", "/form", map[string]string{"X-CSRF-Token": token}, "", cookie, true, http.StatusForbidden},
+		{"exempt", "/webhooks/github", nil, "", nil, false, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest("POST", "http://example.com"+tt.path, strings.NewReader(tt.form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+
+		if tt.cookie != nil {
+			req.AddCookie(tt.cookie)
+		}
+
+		if tt.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.status, w.Code)
+		}
+	}
+}
+
+func TestCSRFTokenMasking(t *testing.T) {
+
+	token := []byte(strings.Repeat("k", csrfTokenSize))
+
+	a, b := maskCSRFToken(token), maskCSRFToken(token)
+
+	if a == b {
+		t.Error("expected masked tokens to differ")
+	}
+
+	if !csrfTokenMatches(a, token) || !csrfTokenMatches(b, token) {
+		t.Error("expected masked tokens to match")
+	}
+}
@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+type ETagOption func(*ETagConfig)
+
+type ETagConfig struct {
+	Weak    bool
+	MaxSize int
+}
+
+// WithWeakETags generates weak instead of strong ETags.
+func WithWeakETags() ETagOption {
+	return func(c *ETagConfig) {
+		c.Weak = true
+	}
+}
+
+// WithMaxBufferSize sets how many bytes of a response are buffered to
+// compute an ETag. Larger responses are sent without one.
+func WithMaxBufferSize(n int) ETagOption {
+	return func(c *ETagConfig) {
+		c.MaxSize = n
+	}
+}
+
+// ETag buffers successful GET and HEAD responses, sets an ETag computed
+// from the body of GET responses unless the handler set one, and answers matching
+// If-None-Match, or If-Modified-Since with a handler supplied Last-Modified,
+// with 304 Not Modified. Responses that are flushed, hijacked, too large or
+// event streams are passed through untouched.
+//
+// Place ETag after Compress in the middleware chain so the ETag describes
+// the uncompressed body.
+func ETag(opts ...ETagOption) router.Middleware {
+
+	config := &ETagConfig{
+		MaxSize: 1 << 20, // 1 MiB
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		ew := &etagWriter{
+			ResponseWriter: w,
+			config:         config,
+			status:         http.StatusOK,
+		}
+
+		next(ew, r)
+
+		ew.finish(r)
+	}
+}
+
+type etagWriter struct {
+	http.ResponseWriter
+	config      *ETagConfig
+	status      int
+	buf         bytes.Buffer
+	passthrough bool
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	w.status = code
+
+	if code != http.StatusOK || strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		w.startPassthrough()
+	}
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+
+	if w.buf.Len()+len(b) > w.config.MaxSize {
+		if err := w.startPassthrough(); err != nil {
+			return 0, err
+		}
+
+		return w.ResponseWriter.Write(b)
+	}
+
+	return w.buf.Write(b)
+}
+
+func (w *etagWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *etagWriter) FlushError() error {
+
+	if err := w.startPassthrough(); err != nil {
+		return err
+	}
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.passthrough = true
+	}
+
+	return conn, brw, err
+}
+
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *etagWriter) startPassthrough() error {
+
+	if w.passthrough {
+		return nil
+	}
+
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf = bytes.Buffer{}
+
+	return err
+}
+
+func (w *etagWriter) finish(r *http.Request) {
+
+	if w.passthrough {
+		return
+	}
+
+	h := w.Header()
+
+	// Handlers usually write no body for HEAD, so a hash of the buffer would
+	// not describe the representation a GET returns.
+	head := r.Method == http.MethodHead
+
+	etag := h.Get("ETag")
+	if etag == "" && !head {
+		sum := sha256.Sum256(w.buf.Bytes())
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+
+		if w.config.Weak {
+			etag = "W/" + etag
+		}
+
+		h.Set("ETag", etag)
+	}
+
+	var lastModified time.Time
+	if lm := h.Get("Last-Modified"); lm != "" {
+		lastModified, _ = http.ParseTime(lm)
+	}
+
+	if router.NotModified(r, etag, lastModified) {
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if head {
+		if h.Get("Content-Length") == "" && w.buf.Len() > 0 {
+			h.Set("Content-Length", strconv.Itoa(w.buf.Len()))
+		}
+
+		w.ResponseWriter.WriteHeader(w.status)
+		return
+	}
+
+	h.Set("Content-Length", strconv.Itoa(w.buf.Len()))
+	w.ResponseWriter.WriteHeader(w.status)
+
+	_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestETag(t *testing.T) {
+
+	m := ETag()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	m(w, req, handler)
+
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || len(etag) != 34 || w.Body.String() != `{"id":1}` {
+		t.Fatalf("unexpected response %d %s %s", w.Code, etag, w.Body.String())
+	}
+
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+
+	m(w, req, handler)
+
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+		t.Error("expected 304 for matching If-None-Match, got ", w.Code)
+	}
+}
+
+func TestETagHead(t *testing.T) {
+
+	m := ETag()
+
+	req, _ := http.NewRequest("HEAD", "/", nil)
+	w := httptest.NewRecorder()
+
+	m(w, req, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "8")
+	})
+
+	if w.Header().Get("ETag") != "" {
+		t.Error("expected no generated ETag for HEAD, got ", w.Header().Get("ETag"))
+	}
+
+	if w.Header().Get("Content-Length") != "8" {
+		t.Error("expected the handler's Content-Length to be kept, got ", w.Header().Get("Content-Length"))
+	}
+
+	req.Header.Set("If-None-Match", `"v1"`)
+	w = httptest.NewRecorder()
+
+	m(w, req, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+	})
+
+	if w.Code != http.StatusNotModified {
+		t.Error("expected 304 for a handler supplied ETag, got ", w.Code)
+	}
+}
+
+func TestETagLastModified(t *testing.T) {
+
+	modified := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+	w := httptest.NewRecorder()
+
+	ETag(WithWeakETags())(w, req, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	if w.Code != http.StatusNotModified {
+		t.Error("expected 304 for unmodified resource, got ", w.Code)
+	}
+
+	if w.Header().Get("ETag")[:2] != "W/" {
+		t.Error("expected a weak ETag")
+	}
+}
+
+func TestETagSkipsStreams(t *testing.T) {
+
+	tests := map[string]http.HandlerFunc{
+		"flushed": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("a"))
+			w.(http.Flusher).Flush()
+		},
+		"too large": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(make([]byte, 32))
+		},
+		"error": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		},
+	}
+
+	for name, handler := range tests {
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+
+		ETag(WithMaxBufferSize(16))(w, req, handler)
+
+		if w.Header().Get("ETag") != "" {
+			t.Errorf("%s: expected no ETag", name)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+type LoggerOption func(*LoggerConfig)
+
+type LoggerConfig struct {
+	SampleRate    float64
+	SkipPaths     []string
+	LogHeaders    bool
+	RedactHeaders []string
+}
+
+// WithSampleRate logs only the given fraction of successful requests.
+// Requests that fail with a 5xx status are always logged.
+func WithSampleRate(rate float64) LoggerOption {
+	return func(c *LoggerConfig) {
+		c.SampleRate = rate
+	}
+}
+
+// WithSkipPaths disables logging for the given paths. A trailing '*'
+// matches any path with that prefix.
+func WithSkipPaths(paths ...string) LoggerOption {
+	return func(c *LoggerConfig) {
+		c.SkipPaths = paths
+	}
+}
+
+// WithLogHeaders adds the request headers to every record.
+func WithLogHeaders() LoggerOption {
+	return func(c *LoggerConfig) {
+		c.LogHeaders = true
+	}
+}
+
+// WithRedactHeaders replaces the values of the given headers in logged
+// records. Authorization, Proxy-Authorization, Cookie and X-API-Key are
+// redacted by default.
+func WithRedactHeaders(headers ...string) LoggerOption {
+	return func(c *LoggerConfig) {
+		c.RedactHeaders = append(c.RedactHeaders, headers...)
+	}
+}
+
+// Logger emits one structured record per request to h. Handlers can log
+// with the request's attributes through router.Log.
+func Logger(h slog.Handler, opts ...LoggerOption) router.Middleware {
+
+	config := &LoggerConfig{
+		SampleRate:    1,
+		RedactHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie", "X-API-Key"},
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	redact := make(map[string]bool, len(config.RedactHeaders))
+	for _, name := range config.RedactHeaders {
+		redact[http.CanonicalHeaderKey(name)] = true
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+
+		if skipPath(config.SkipPaths, r.URL.Path) {
+			next(w, r)
+			return
+		}
+
+		start := time.Now()
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("route", router.RoutePattern(r)),
+			slog.String("remote_ip", remoteIP(r)),
+		}
+
+		if id := router.RequestID(r); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+
+		logger := slog.New(h.WithAttrs(attrs))
+
+		rw := WrapResponseWriter(w)
+
+		next(rw, router.SetLogger(r, logger))
+
+		status := rw.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		if status < 500 && config.SampleRate < 1 && rand.Float64() >= config.SampleRate {
+			return
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		record := []slog.Attr{
+			slog.Int("status", status),
+			slog.Int64("bytes", rw.BytesWritten()),
+			slog.Duration("duration", time.Since(start)),
+		}
+
+		if config.LogHeaders {
+			record = append(record, headerAttrs(r.Header, redact))
+		}
+
+		logger.LogAttrs(r.Context(), level, "request", record...)
+	}
+}
+
+func headerAttrs(h http.Header, redact map[string]bool) slog.Attr {
+
+	attrs := make([]interface{}, 0, len(h))
+
+	for name, values := range h {
+		value := strings.Join(values, ", ")
+
+		if redact[name] {
+			value = "[REDACTED]"
+		}
+
+		attrs = append(attrs, slog.String(name, value))
+	}
+
+	return slog.Group("headers", attrs...)
+}
+
+func skipPath(paths []string, path string) bool {
+
+	for _, p := range paths {
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(path, p[:len(p)-1]) {
+				return true
+			}
+		} else if p == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+func remoteIP(r *http.Request) string {
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
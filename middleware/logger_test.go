@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+func TestLogger(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	r := router.New()
+
+	r.Use(RequestID(), Logger(slog.NewJSONHandler(&buf, nil), WithLogHeaders()))
+
+	r.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		router.Log(r).Info("loading user")
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	req, _ := http.NewRequest("GET", "/users/42", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-API-Key", "secret")
+	req.Header.Set("X-Request-ID", "abc")
+	req.RemoteAddr = "10.0.0.1:1234"
+
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatal("expected 2 log records, got ", len(lines))
+	}
+
+	var handlerRecord, accessRecord map[string]interface{}
+	_ = json.Unmarshal([]byte(lines[0]), &handlerRecord)
+	_ = json.Unmarshal([]byte(lines[1]), &accessRecord)
+
+	if handlerRecord["route"] != "/users/:id" || handlerRecord["request_id"] != "abc" {
+		t.Error("handler logger is missing request attributes: ", lines[0])
+	}
+
+	if accessRecord["status"] != float64(404) || accessRecord["level"] != "WARN" || accessRecord["remote_ip"] != "10.0.0.1" {
+		t.Error("unexpected access record: ", lines[1])
+	}
+
+	headers := accessRecord["headers"].(map[string]interface{})
+	if headers["Authorization"] != "[REDACTED]" {
+		t.Error("authorization header was not redacted: ", headers["Authorization"])
+	}
+
+	if headers["X-Api-Key"] != "[REDACTED]" {
+		t.Error("api key header was not redacted: ", headers["X-Api-Key"])
+	}
+}
+
+func TestLoggerUnmatchedRoutes(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	r := router.New()
+
+	r.Use(RequestID(), Logger(slog.NewJSONHandler(&buf, nil)))
+
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"GET", "/missing", http.StatusNotFound},
+		{"POST", "/users", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+
+		buf.Reset()
+
+		req, _ := http.NewRequest(tt.method, tt.path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Fatalf("%s %s: expected %d, got %d", tt.method, tt.path, tt.status, w.Code)
+		}
+
+		if w.Header().Get("X-Request-ID") == "" {
+			t.Errorf("%s %s: expected a request ID", tt.method, tt.path)
+		}
+
+		var record map[string]interface{}
+		_ = json.Unmarshal(buf.Bytes(), &record)
+
+		if record["status"] != float64(tt.status) || record["request_id"] == nil {
+			t.Errorf("%s %s: unexpected access record: %s", tt.method, tt.path, buf.String())
+		}
+	}
+}
+
+func TestLoggerIgnoresUnvalidatedRequestID(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "forged\nline")
+
+	Logger(slog.NewJSONHandler(&buf, nil))(httptest.NewRecorder(), req, func(w http.ResponseWriter, r *http.Request) {})
+
+	if strings.Contains(buf.String(), "request_id") {
+		t.Error("expected the raw X-Request-ID header not to be logged: ", buf.String())
+	}
+}
+
+func TestLoggerSkipPaths(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	m := Logger(slog.NewJSONHandler(&buf, nil), WithSkipPaths("/health", "/static/*"), WithSampleRate(0))
+
+	for _, path := range []string{"/health", "/static/app.js", "/sampled"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		m(httptest.NewRecorder(), req, func(w http.ResponseWriter, r *http.Request) {})
+	}
+
+	req, _ := http.NewRequest("GET", "/failing", nil)
+	m(httptest.NewRecorder(), req, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	if strings.Count(buf.String(), "\n") != 1 || !strings.Contains(buf.String(), `"status":500`) {
+		t.Error("expected only the failing request to be logged, got ", buf.String())
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+// RequirePreconditions rejects PUT, PATCH and DELETE requests that carry no
+// If-Match, If-Unmodified-Since or If-None-Match header with 428
+// Precondition Required. Handlers then evaluate the preconditions with
+// router.CheckPreconditions.
+func RequirePreconditions() router.Middleware {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+
+		switch r.Method {
+		case http.MethodPut, http.MethodPatch, http.MethodDelete:
+			if !router.IsConditional(r) {
+				router.Error(w, r, router.ErrPreconditionRequired)
+				return
+			}
+		}
+
+		next(w, r)
+	}
+}
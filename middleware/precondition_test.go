@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+func TestRequirePreconditions(t *testing.T) {
+
+	r := router.New()
+
+	r.Put("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		if router.CheckPreconditions(w, r, `"v2"`, time.Time{}) {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}).Use(RequirePreconditions())
+
+	tests := []struct {
+		ifMatch string
+		status  int
+	}{
+		{"", http.StatusPreconditionRequired},
+		{`"v1"`, http.StatusPreconditionFailed},
+		{`"v2"`, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest("PUT", "/users/1", nil)
+		if tt.ifMatch != "" {
+			req.Header.Set("If-Match", tt.ifMatch)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("If-Match %q: expected %d, got %d", tt.ifMatch, tt.status, w.Code)
+		}
+	}
+}
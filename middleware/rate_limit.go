@@ -0,0 +1,341 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket allows bursts of up to Limit requests and refills at
+	// Limit per Window.
+	TokenBucket RateLimitAlgorithm = iota
+
+	// SlidingWindow allows Limit requests in any Window, estimated from the
+	// counts of the current and previous fixed windows.
+	SlidingWindow
+)
+
+type RateLimitRule struct {
+	Algorithm RateLimitAlgorithm
+	Limit     int
+	Window    time.Duration
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the rate limit state per key. Implementations backed
+// by a shared database allow limits to be enforced across instances.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, rule RateLimitRule, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimitKeyFunc returns the key a request is counted against.
+type RateLimitKeyFunc func(r *http.Request) string
+
+type RateLimitOption func(*RateLimitConfig)
+
+type RateLimitConfig struct {
+	Rule    RateLimitRule
+	Key     RateLimitKeyFunc
+	Store   RateLimitStore
+	Handler http.HandlerFunc
+}
+
+func WithAlgorithm(algorithm RateLimitAlgorithm) RateLimitOption {
+	return func(c *RateLimitConfig) {
+		c.Rule.Algorithm = algorithm
+	}
+}
+
+func WithKey(key RateLimitKeyFunc) RateLimitOption {
+	return func(c *RateLimitConfig) {
+		c.Key = key
+	}
+}
+
+func WithStore(store RateLimitStore) RateLimitOption {
+	return func(c *RateLimitConfig) {
+		c.Store = store
+	}
+}
+
+// WithLimitExceededHandler sets the handler for rejected requests. The
+// rate limit headers are set before it is called.
+func WithLimitExceededHandler(handler http.HandlerFunc) RateLimitOption {
+	return func(c *RateLimitConfig) {
+		c.Handler = handler
+	}
+}
+
+// RateLimit allows limit requests per window for each key, which is the
+// client IP by default. Apply it with Use on a router, group or route to
+// scope the limit. Responses carry RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers, and rejected requests get 429 with Retry-After.
+// If the store fails the request is let through. It panics unless limit and
+// window are positive.
+func RateLimit(limit int, window time.Duration, opts ...RateLimitOption) router.Middleware {
+
+	if limit <= 0 || window <= 0 {
+		panic(fmt.Sprintf("middleware: rate limit needs a positive limit and window, got %d per %s", limit, window))
+	}
+
+	config := &RateLimitConfig{
+		Rule: RateLimitRule{
+			Algorithm: TokenBucket,
+			Limit:     limit,
+			Window:    window,
+		},
+		Key: KeyByIP,
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			router.Error(w, r, router.NewHTTPError(http.StatusTooManyRequests, "rate_limited", "rate limit exceeded"))
+		},
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore()
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+
+		res, err := config.Store.Allow(r.Context(), config.Key(r), config.Rule, time.Now())
+		if err != nil {
+			next(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			config.Handler(w, r)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func KeyByIP(r *http.Request) string {
+	return remoteIP(r)
+}
+
+// KeyByRoute counts all requests to the same route pattern together.
+func KeyByRoute(r *http.Request) string {
+	return r.Method + " " + router.RoutePattern(r)
+}
+
+func KeyByHeader(name string) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// KeyByLocal counts requests by a request local set by earlier middleware,
+// such as an authenticated user ID, falling back to the client IP.
+func KeyByLocal(name string) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		if v, ok := router.GetLocal(r, name).(string); ok && v != "" {
+			return v
+		}
+		return remoteIP(r)
+	}
+}
+
+// KeyByPrincipal counts requests by the subject of the authenticated
+// principal, falling back to the client IP for anonymous requests.
+func KeyByPrincipal(r *http.Request) string {
+	if id := router.Principal(r); id != nil {
+		return id.Scheme + ":" + id.Subject
+	}
+	return remoteIP(r)
+}
+
+// CombineKeys joins the keys of several key functions, for example to limit
+// each client per route.
+func CombineKeys(keys ...RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		var k string
+		for i, key := range keys {
+			if i > 0 {
+				k += "|"
+			}
+			k += key(r)
+		}
+		return k
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+
+	if d <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(d.Seconds()))
+}
+
+const rateLimitShards = 32
+
+// MemoryRateLimitStore is an in-process RateLimitStore. Keys are spread
+// over shards to reduce lock contention, and idle keys are evicted once
+// their state has fully expired.
+type MemoryRateLimitStore struct {
+	shards [rateLimitShards]rateLimitShard
+}
+
+type rateLimitShard struct {
+	mu        sync.Mutex
+	entries   map[string]*rateLimitEntry
+	lastSweep time.Time
+}
+
+type rateLimitEntry struct {
+	// Token bucket state.
+	tokens float64
+	last   time.Time
+
+	// Sliding window state.
+	windowStart time.Time
+	current     int
+	previous    int
+
+	expires time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+
+	s := &MemoryRateLimitStore{}
+
+	for i := range s.shards {
+		s.shards[i].entries = map[string]*rateLimitEntry{}
+	}
+
+	return s
+}
+
+func (s *MemoryRateLimitStore) Allow(_ context.Context, key string, rule RateLimitRule, now time.Time) (RateLimitResult, error) {
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	shard := &s.shards[h.Sum32()%rateLimitShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if now.Sub(shard.lastSweep) >= rule.Window {
+		shard.sweep(now)
+	}
+
+	e, ok := shard.entries[key]
+	if !ok {
+		e = &rateLimitEntry{tokens: float64(rule.Limit), last: now, windowStart: now}
+		shard.entries[key] = e
+	}
+
+	if rule.Algorithm == SlidingWindow {
+		return e.slidingWindow(rule, now), nil
+	}
+
+	return e.tokenBucket(rule, now), nil
+}
+
+func (s *rateLimitShard) sweep(now time.Time) {
+
+	for key, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+
+	s.lastSweep = now
+}
+
+func (e *rateLimitEntry) tokenBucket(rule RateLimitRule, now time.Time) RateLimitResult {
+
+	limit := float64(rule.Limit)
+	perToken := rule.Window / time.Duration(rule.Limit)
+
+	if elapsed := now.Sub(e.last); elapsed > 0 {
+		e.tokens = math.Min(limit, e.tokens+elapsed.Seconds()/perToken.Seconds())
+		e.last = now
+	}
+
+	res := RateLimitResult{Limit: rule.Limit}
+
+	if e.tokens >= 1 {
+		e.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - e.tokens) * float64(perToken))
+	}
+
+	res.Remaining = int(e.tokens)
+	res.Reset = time.Duration((limit - e.tokens) * float64(perToken))
+	e.expires = now.Add(res.Reset)
+
+	return res
+}
+
+func (e *rateLimitEntry) slidingWindow(rule RateLimitRule, now time.Time) RateLimitResult {
+
+	if elapsed := now.Sub(e.windowStart); elapsed >= rule.Window {
+		windows := int(elapsed / rule.Window)
+
+		e.previous = e.current
+		if windows > 1 {
+			e.previous = 0
+		}
+
+		e.current = 0
+		e.windowStart = e.windowStart.Add(time.Duration(windows) * rule.Window)
+	}
+
+	elapsed := now.Sub(e.windowStart)
+	weight := 1 - float64(elapsed)/float64(rule.Window)
+	count := float64(e.previous)*weight + float64(e.current)
+
+	res := RateLimitResult{
+		Limit: rule.Limit,
+		Reset: rule.Window - elapsed,
+	}
+
+	if count+1 <= float64(rule.Limit) {
+		e.current++
+		count++
+		res.Allowed = true
+	} else if e.previous > 0 && e.current < rule.Limit {
+		// Wait until enough of the previous window has slid out.
+		needed := (count + 1 - float64(rule.Limit)) / float64(e.previous)
+		res.RetryAfter = time.Duration(needed * float64(rule.Window))
+	} else {
+		res.RetryAfter = res.Reset
+	}
+
+	res.Remaining = int(math.Max(0, math.Floor(float64(rule.Limit)-count)))
+	e.expires = e.windowStart.Add(2 * rule.Window)
+
+	return res
+}
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+func TestRateLimit(t *testing.T) {
+
+	r := router.New()
+
+	api := r.Group("/api")
+	api.Use(RateLimit(2, time.Minute))
+	api.Get("/items", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+
+	var w *httptest.ResponseRecorder
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "/api/items", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w = httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if i < 2 && w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, w.Code)
+		}
+	}
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatal("expected 429, got ", w.Code)
+	}
+
+	if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Error("unexpected rate limit headers: ", w.Header())
+	}
+
+	if w.Header().Get("Retry-After") != "30" {
+		t.Error("unexpected Retry-After: ", w.Header().Get("Retry-After"))
+	}
+
+	req, _ := http.NewRequest("GET", "/health", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	w = httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Error("rate limit must only apply to the group")
+	}
+}
+
+func TestRateLimit_KeyByPrincipal(t *testing.T) {
+
+	r := router.New()
+
+	r.Use(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if user := r.Header.Get("X-User"); user != "" {
+			r = router.SetPrincipal(r, &router.Identity{Subject: user, Scheme: "test"})
+		}
+		next(w, r)
+	})
+	r.Use(RateLimit(1, time.Minute, WithKey(KeyByPrincipal)))
+	r.Get("/items", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		user string
+		addr string
+		code int
+	}{
+		{"alice", "10.0.0.1:1234", http.StatusOK},
+		{"alice", "10.0.0.2:1234", http.StatusTooManyRequests},
+		{"bob", "10.0.0.1:1234", http.StatusOK},
+		{"", "10.0.0.1:1234", http.StatusOK},
+		{"", "10.0.0.1:5678", http.StatusTooManyRequests},
+	}
+
+	for i, tt := range tests {
+
+		req, _ := http.NewRequest("GET", "/items", nil)
+		req.RemoteAddr = tt.addr
+		if tt.user != "" {
+			req.Header.Set("X-User", tt.user)
+		}
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.code {
+			t.Errorf("request %d: expected %d, got %d", i, tt.code, w.Code)
+		}
+	}
+}
+
+func TestRateLimitInvalid(t *testing.T) {
+
+	for _, tt := range []struct {
+		limit  int
+		window time.Duration
+	}{
+		{0, time.Minute},
+		{-1, time.Minute},
+		{10, 0},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected RateLimit(%d, %s) to panic", tt.limit, tt.window)
+				}
+			}()
+
+			RateLimit(tt.limit, tt.window)
+		}()
+	}
+}
+
+func TestMemoryRateLimitStore_TokenBucket(t *testing.T) {
+
+	store := NewMemoryRateLimitStore()
+	rule := RateLimitRule{Algorithm: TokenBucket, Limit: 10, Window: 10 * time.Second}
+	now := time.Now()
+
+	for i := 0; i < 10; i++ {
+		if res, _ := store.Allow(context.Background(), "k", rule, now); !res.Allowed {
+			t.Fatal("expected burst of 10 to be allowed")
+		}
+	}
+
+	if res, _ := store.Allow(context.Background(), "k", rule, now); res.Allowed || res.RetryAfter != time.Second {
+		t.Fatal("expected to wait one second for the next token, got ", res.RetryAfter)
+	}
+
+	if res, _ := store.Allow(context.Background(), "k", rule, now.Add(time.Second)); !res.Allowed {
+		t.Fatal("expected a token to be refilled after one second")
+	}
+}
+
+func TestMemoryRateLimitStore_SlidingWindow(t *testing.T) {
+
+	store := NewMemoryRateLimitStore()
+	rule := RateLimitRule{Algorithm: SlidingWindow, Limit: 4, Window: time.Minute}
+	now := time.Now()
+
+	for i := 0; i < 4; i++ {
+		if res, _ := store.Allow(context.Background(), "k", rule, now); !res.Allowed {
+			t.Fatal("expected 4 requests to be allowed")
+		}
+	}
+
+	// Half way into the next window half of the previous count remains.
+	now = now.Add(90 * time.Second)
+
+	for i := 0; i < 2; i++ {
+		if res, _ := store.Allow(context.Background(), "k", rule, now); !res.Allowed {
+			t.Fatal("expected 2 more requests to be allowed")
+		}
+	}
+
+	if res, _ := store.Allow(context.Background(), "k", rule, now); res.Allowed {
+		t.Fatal("expected the sliding window to be full")
+	}
+}
+
+func TestMemoryRateLimitStore_Eviction(t *testing.T) {
+
+	store := NewMemoryRateLimitStore()
+	rule := RateLimitRule{Algorithm: TokenBucket, Limit: 1, Window: time.Second}
+	now := time.Now()
+
+	for i := 0; i < 1000; i++ {
+		_, _ = store.Allow(context.Background(), strconv.Itoa(i), rule, now)
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte("0"))
+	shard := &store.shards[h.Sum32()%rateLimitShards]
+
+	if len(shard.entries) < 2 {
+		t.Fatal("expected several entries in the shard")
+	}
+
+	_, _ = store.Allow(context.Background(), "0", rule, now.Add(time.Hour))
+
+	if len(shard.entries) != 1 {
+		t.Error("expected expired entries to be evicted, found ", len(shard.entries))
+	}
+}
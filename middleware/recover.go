@@ -19,12 +19,17 @@ func WithHandler(handler router.ErrorHandler) RecoverOption {
 	}
 }
 
+// WithProblemDetails renders recovered panics as RFC 9457 problem details.
+func WithProblemDetails() RecoverOption {
+	return func(c *RecoverConfig) {
+		c.ErrorHandler = router.ProblemErrorHandler
+	}
+}
+
 func Recover(opts ...RecoverOption) router.Middleware {
 
 	config := &RecoverConfig{
-		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		},
+		ErrorHandler: router.Error,
 	}
 
 	for _, opt := range opts {
@@ -28,3 +28,23 @@ func TestRecover(t *testing.T) {
 		t.Fatalf("expected error, got nil")
 	}
 }
+
+func TestRecoverWithProblemDetails(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	m := Recover(WithProblemDetails())
+
+	m(w, req, func(w http.ResponseWriter, r *http.Request) {
+		panic("something went wrong")
+	})
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status code %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+
+	if w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("expected problem details, got %s", w.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,233 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+type RequestIDOption func(*RequestIDConfig)
+
+type RequestIDConfig struct {
+	HeaderName    string
+	Generator     func() string
+	TrustIncoming bool
+}
+
+func WithRequestIDHeader(name string) RequestIDOption {
+	return func(c *RequestIDConfig) {
+		c.HeaderName = name
+	}
+}
+
+func WithRequestIDGenerator(generator func() string) RequestIDOption {
+	return func(c *RequestIDConfig) {
+		c.Generator = generator
+	}
+}
+
+// WithTrustIncoming controls whether IDs sent by the client are reused.
+func WithTrustIncoming(trust bool) RequestIDOption {
+	return func(c *RequestIDConfig) {
+		c.TrustIncoming = trust
+	}
+}
+
+// RequestID assigns every request an ID, available through router.RequestID
+// and echoed in the response header. A valid incoming X-Request-ID is
+// reused, followed by the trace ID of a W3C traceparent header. Otherwise a
+// new ULID is generated.
+func RequestID(opts ...RequestIDOption) router.Middleware {
+
+	config := &RequestIDConfig{
+		HeaderName:    "X-Request-ID",
+		Generator:     NewULID,
+		TrustIncoming: true,
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+
+		var id string
+
+		if config.TrustIncoming {
+			if v := r.Header.Get(config.HeaderName); validRequestID(v) {
+				id = v
+			} else if traceID, ok := parseTraceParent(r.Header.Get("traceparent")); ok {
+				id = traceID
+			}
+		}
+
+		if id == "" {
+			id = config.Generator()
+		}
+
+		w.Header().Set(config.HeaderName, id)
+
+		next(w, router.SetRequestID(r, id))
+	}
+}
+
+// RequestIDTransport sets the X-Request-ID header on outgoing requests whose
+// context carries a request ID. WithRequestIDHeader changes the header, the
+// other options are ignored. A nil base uses http.DefaultTransport.
+func RequestIDTransport(base http.RoundTripper, opts ...RequestIDOption) http.RoundTripper {
+
+	config := &RequestIDConfig{
+		HeaderName: "X-Request-ID",
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+
+		id := router.RequestIDFromContext(req.Context())
+		if id == "" || req.Header.Get(config.HeaderName) != "" {
+			return base.RoundTrip(req)
+		}
+
+		// A RoundTripper must not modify the request it was given.
+		req = req.Clone(req.Context())
+		req.Header.Set(config.HeaderName, id)
+
+		return base.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func validRequestID(id string) bool {
+
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !isAlphaNum(c) && c != '-' && c != '_' && c != '.' && c != ':' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseTraceParent returns the trace ID of a W3C traceparent header value.
+func parseTraceParent(v string) (string, bool) {
+
+	parts := strings.Split(v, "-")
+	if len(parts) < 4 {
+		return "", false
+	}
+
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+
+	if len(version) != 2 || !isLowerHex(version) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return "", false
+	}
+
+	if len(traceID) != 32 || !isLowerHex(traceID) || strings.Trim(traceID, "0") == "" {
+		return "", false
+	}
+
+	if len(parentID) != 16 || !isLowerHex(parentID) || strings.Trim(parentID, "0") == "" {
+		return "", false
+	}
+
+	if len(flags) != 2 || !isLowerHex(flags) {
+		return "", false
+	}
+
+	return traceID, true
+}
+
+func isLowerHex(s string) bool {
+
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9') && !('a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isAlphaNum(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var ulidState struct {
+	sync.Mutex
+	ms      uint64
+	entropy [10]byte
+}
+
+// NewULID returns a 26 character ULID. IDs generated within the same
+// millisecond are monotonically increasing, so IDs sort by creation time.
+func NewULID() string {
+
+	ms := uint64(time.Now().UnixMilli())
+
+	ulidState.Lock()
+
+	if ms <= ulidState.ms {
+		// Same (or earlier, if the clock went back) millisecond: increment
+		// the previous entropy instead of drawing new randomness.
+		ms = ulidState.ms
+		for i := len(ulidState.entropy) - 1; i >= 0; i-- {
+			ulidState.entropy[i]++
+			if ulidState.entropy[i] != 0 {
+				break
+			}
+		}
+	} else {
+		ulidState.ms = ms
+		_, _ = rand.Read(ulidState.entropy[:])
+	}
+
+	var b [16]byte
+	binary.BigEndian.PutUint16(b[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:], uint32(ms))
+	copy(b[6:], ulidState.entropy[:])
+
+	ulidState.Unlock()
+
+	return encodeULID(b)
+}
+
+// encodeULID encodes 128 bits as 26 Crockford base32 characters.
+func encodeULID(b [16]byte) string {
+
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+
+	var out [26]byte
+
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(out[:])
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/ironfang-ltd/router-go"
+)
+
+func TestRequestID(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		expected string
+	}{
+		{"incoming", "X-Request-ID", "abc-123", "abc-123"},
+		{"invalid incoming", "X-Request-ID", "abc 123\n", ""},
+		{"traceparent", "traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"invalid traceparent", "traceparent", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", ""},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set(tt.header, tt.value)
+		w := httptest.NewRecorder()
+
+		var id string
+
+		RequestID()(w, req, func(w http.ResponseWriter, r *http.Request) {
+			id = router.RequestID(r)
+		})
+
+		if tt.expected != "" && id != tt.expected {
+			t.Errorf("%s: expected id %s, got %s", tt.name, tt.expected, id)
+		}
+
+		if tt.expected == "" && len(id) != 26 {
+			t.Errorf("%s: expected a generated ULID, got %s", tt.name, id)
+		}
+
+		if w.Header().Get("X-Request-ID") != id {
+			t.Errorf("%s: id was not echoed in the response", tt.name)
+		}
+	}
+}
+
+func TestNewULID(t *testing.T) {
+
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = NewULID()
+	}
+
+	if !sort.StringsAreSorted(ids) {
+		t.Error("expected ULIDs to be sortable by creation order")
+	}
+
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			t.Fatal("duplicate ULID ", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestRequestIDTransport(t *testing.T) {
+
+	var received string
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("X-Request-ID")
+	}))
+	defer s.Close()
+
+	client := &http.Client{Transport: RequestIDTransport(nil)}
+
+	incoming, _ := http.NewRequest("GET", "/", nil)
+	incoming = router.SetRequestID(incoming, "req-1")
+
+	req, _ := http.NewRequestWithContext(incoming.Context(), "GET", s.URL, nil)
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if received != "req-1" {
+		t.Error("expected request id to be forwarded, got ", received)
+	}
+
+	if req.Header.Get("X-Request-ID") != "" {
+		t.Error("transport must not modify the original request")
+	}
+}
+
+func TestRequestIDTransportWithCustomHeader(t *testing.T) {
+
+	var received string
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("X-Correlation-ID")
+	}))
+	defer s.Close()
+
+	client := &http.Client{Transport: RequestIDTransport(nil, WithRequestIDHeader("X-Correlation-ID"))}
+
+	incoming, _ := http.NewRequest("GET", "/", nil)
+	incoming = router.SetRequestID(incoming, "req-1")
+
+	req, _ := http.NewRequestWithContext(incoming.Context(), "GET", s.URL, nil)
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if received != "req-1" {
+		t.Error("expected request id to be forwarded, got ", received)
+	}
+}
@@ -33,9 +33,21 @@ func RequestTime(opts ...RequestTimeOption) router.Middleware {
 	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 
 		start := time.Now()
-		next(w, r)
-		taken := time.Since(start)
 
-		w.Header().Set(config.HeaderName, strconv.FormatInt(taken.Milliseconds(), 10))
+		setHeader := func(int) {
+			taken := time.Since(start)
+			w.Header().Set(config.HeaderName, strconv.FormatInt(taken.Milliseconds(), 10))
+		}
+
+		rw := WrapResponseWriter(w)
+		rw.BeforeWrite(setHeader)
+
+		next(rw, r)
+
+		// The handler did not write anything, so the header is still
+		// pending and the time taken is the full handler duration.
+		if !rw.HeaderWritten() {
+			setHeader(0)
+		}
 	}
 }
@@ -36,3 +36,19 @@ func TestRequestTimeWithCustomHeader(t *testing.T) {
 		t.Fatal("expected custom header X-Request-Time to be set")
 	}
 }
+
+func TestRequestTimeBeforeWrite(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	m := RequestTime()
+
+	m(w, req, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	if w.Result().Header.Get("X-Request-Time-Ms") == "" {
+		t.Fatal("expected header X-Request-Time-Ms to be sent with the response")
+	}
+}
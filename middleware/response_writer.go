@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ResponseWriter wraps an http.ResponseWriter and records the status code,
+// the number of bytes written and when the first byte was written. It
+// implements http.Flusher, http.Hijacker and io.ReaderFrom by delegating to
+// the wrapped writer, and Unwrap so that http.ResponseController keeps
+// working through it.
+//
+// The methods are present even when the wrapped writer lacks them, so a type
+// assertion is not proof of support. Flush is then a no-op, FlushError and
+// Hijack return http.ErrNotSupported and ReadFrom falls back to io.Copy.
+// Prefer http.ResponseController, which reports the error.
+type ResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+	hijacked    bool
+	firstByte   time.Time
+	beforeWrite []func(status int)
+}
+
+// WrapResponseWriter wraps w. If w is already a *ResponseWriter it is
+// returned as is, so that middleware share a single wrapper.
+func WrapResponseWriter(w http.ResponseWriter) *ResponseWriter {
+
+	if rw, ok := w.(*ResponseWriter); ok {
+		return rw
+	}
+
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+// Status returns the status code sent to the client, or 0 if the header has
+// not been written yet.
+func (w *ResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *ResponseWriter) BytesWritten() int64 {
+	return w.bytes
+}
+
+func (w *ResponseWriter) HeaderWritten() bool {
+	return w.wroteHeader
+}
+
+func (w *ResponseWriter) Hijacked() bool {
+	return w.hijacked
+}
+
+// FirstByte returns when the header was written, or the zero time.
+func (w *ResponseWriter) FirstByte() time.Time {
+	return w.firstByte
+}
+
+// BeforeWrite registers fn to be called just before the header is written,
+// while headers may still be changed. Functions run in the order they were
+// registered.
+func (w *ResponseWriter) BeforeWrite(fn func(status int)) {
+	w.beforeWrite = append(w.beforeWrite, fn)
+}
+
+func (w *ResponseWriter) WriteHeader(code int) {
+
+	if w.wroteHeader || w.hijacked {
+		return
+	}
+
+	// Informational responses other than 101 may be followed by the real
+	// response, so they do not count as writing the header.
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	hooks := w.beforeWrite
+	w.beforeWrite = nil
+
+	for _, fn := range hooks {
+		fn(code)
+	}
+
+	w.wroteHeader = true
+	w.status = code
+	w.firstByte = time.Now()
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+
+	return n, err
+}
+
+func (w *ResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	var n int64
+	var err error
+
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(writerOnly{w.ResponseWriter}, r)
+	}
+
+	w.bytes += n
+
+	return n, err
+}
+
+func (w *ResponseWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError flushes the wrapped writer and is used by
+// http.ResponseController.
+func (w *ResponseWriter) FlushError() error {
+
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+
+		if w.status == 0 {
+			w.status = http.StatusSwitchingProtocols
+		}
+	}
+
+	return conn, brw, err
+}
+
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// writerOnly hides any ReadFrom method of the writer so io.Copy does not
+// call back into ResponseWriter.ReadFrom.
+type writerOnly struct {
+	io.Writer
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseWriter(t *testing.T) {
+
+	rec := httptest.NewRecorder()
+	w := WrapResponseWriter(rec)
+
+	if WrapResponseWriter(w) != w {
+		t.Fatal("expected wrapping a ResponseWriter to return it")
+	}
+
+	var hookStatus int
+
+	w.BeforeWrite(func(status int) {
+		hookStatus = status
+		w.Header().Set("X-Hook", "true")
+	})
+
+	if w.HeaderWritten() {
+		t.Fatal("header must not be written before the first write")
+	}
+
+	_, _ = w.Write([]byte("hello"))
+	_, _ = w.ReadFrom(strings.NewReader(" world"))
+
+	if hookStatus != http.StatusOK || rec.Header().Get("X-Hook") != "true" {
+		t.Error("before write hook did not run")
+	}
+
+	if w.Status() != http.StatusOK || w.BytesWritten() != 11 || w.FirstByte().IsZero() {
+		t.Error("unexpected recorded state: ", w.Status(), w.BytesWritten())
+	}
+
+	if err := http.NewResponseController(w).Flush(); err != nil || !rec.Flushed {
+		t.Error("flush did not reach the wrapped writer: ", err)
+	}
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestResponseWriter_Hijack(t *testing.T) {
+
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	w := WrapResponseWriter(rec)
+
+	if _, _, err := http.NewResponseController(w).Hijack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !rec.hijacked || !w.Hijacked() {
+		t.Error("hijack did not reach the wrapped writer")
+	}
+
+	if _, _, err := WrapResponseWriter(httptest.NewRecorder()).Hijack(); err == nil {
+		t.Error("expected hijack of a non-hijacker to fail")
+	}
+}
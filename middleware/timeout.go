@@ -17,16 +17,20 @@ func Timeout(timeout time.Duration) router.Middleware {
 
 		ctx, cancel := context.WithTimeout(r.Context(), timeout)
 
+		rw := WrapResponseWriter(w)
+
 		defer func() {
 
 			// Calling cancel() on a canceled context is a noop.
 			cancel()
 
-			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-				w.WriteHeader(http.StatusGatewayTimeout)
+			// Only report the timeout if the handler has not started its
+			// own response.
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && !rw.HeaderWritten() && !rw.Hijacked() {
+				rw.WriteHeader(http.StatusGatewayTimeout)
 			}
 		}()
 
-		next(w, r.WithContext(ctx))
+		next(rw, r.WithContext(ctx))
 	}
 }
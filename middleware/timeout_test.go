@@ -31,3 +31,20 @@ func TestTimeout(t *testing.T) {
 		t.Fatalf("expected status code %d, got %d", http.StatusGatewayTimeout, w.Code)
 	}
 }
+
+func TestTimeoutAfterWrite(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	m := Timeout(10 * time.Millisecond)
+
+	m(w, req, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		<-r.Context().Done()
+	})
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status code %d, got %d", http.StatusAccepted, w.Code)
+	}
+}
@@ -0,0 +1,103 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type routeSet []route
+
+func (s routeSet) Use(m ...Middleware) {
+	for _, route := range s {
+		route.Use(m...)
+	}
+}
+
+func (s routeSet) Require(requirements ...string) {
+	for _, route := range s {
+		route.Require(requirements...)
+	}
+}
+
+func (r *router) Mount(prefix string, handler http.Handler) Route {
+
+	h := mountHandler(r.getPrefix()+prefix, handler)
+
+	if prefix == "/" {
+		return routeSet{
+			r.mapMethod("*", "/", h),
+			r.mapMethod("*", "/*", h),
+		}
+	}
+
+	return routeSet{
+		r.mapMethod("*", prefix, h),
+		r.mapMethod("*", prefix+"/*", h),
+	}
+}
+
+func (r *router) getPrefix() string {
+
+	if r.parent == nil {
+		return r.prefix
+	}
+
+	return r.parent.getPrefix() + r.prefix
+}
+
+// mountHandler strips the mount prefix from the request path before handing
+// the request to handler. The prefix is counted in segments rather than
+// compared literally so that mount points may contain route params.
+func mountHandler(prefix string, handler http.Handler) http.HandlerFunc {
+
+	segments := strings.Count(strings.TrimSuffix(prefix, "/"), "/")
+
+	return func(w http.ResponseWriter, req *http.Request) {
+
+		matched, rest := splitSegments(req.URL.Path, segments)
+
+		ctx := context.WithValue(req.Context(), contextKeyMount, MountPrefix(req)+matched)
+		req2 := req.WithContext(ctx)
+
+		u := *req.URL
+		u.Path = rest
+		u.RawPath = ""
+
+		if req.URL.RawPath != "" {
+			_, rawRest := splitSegments(req.URL.RawPath, segments)
+
+			if p, err := url.PathUnescape(rawRest); err == nil && p == rest {
+				u.RawPath = rawRest
+			}
+		}
+
+		req2.URL = &u
+
+		handler.ServeHTTP(w, req2)
+	}
+}
+
+// splitSegments splits p after its first n segments. The remainder is always
+// an absolute path.
+func splitSegments(p string, n int) (string, string) {
+
+	i := 0
+
+	for k := 0; k < n && i < len(p); k++ {
+		next := strings.IndexByte(p[i+1:], PathSep)
+		if next == -1 {
+			i = len(p)
+			break
+		}
+
+		i += next + 1
+	}
+
+	if i >= len(p) {
+		return p, "/"
+	}
+
+	return p[:i], p[i:]
+}
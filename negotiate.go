@@ -0,0 +1,272 @@
+package router
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder writes v to w with the given status code, like JSON and XML.
+type Encoder func(w http.ResponseWriter, r *http.Request, code int, v interface{}) error
+
+type encoderEntry struct {
+	mediaType string
+	encode    Encoder
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = []encoderEntry{
+		{"application/json", JSON},
+		{"application/xml", XML},
+		{"text/csv", CSV},
+		{"text/plain", func(w http.ResponseWriter, r *http.Request, code int, v interface{}) error {
+			return Text(w, r, code, fmt.Sprint(v))
+		}},
+	}
+)
+
+var ErrNotAcceptable = NewHTTPError(http.StatusNotAcceptable, "not_acceptable", "no acceptable representation")
+
+// RegisterEncoder makes encode available to Respond for mediaType. Encoders
+// registered for an existing media type replace it.
+func RegisterEncoder(mediaType string, encode Encoder) {
+
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	for i := range encoders {
+		if encoders[i].mediaType == mediaType {
+			encoders[i].encode = encode
+			return
+		}
+	}
+
+	encoders = append(encoders, encoderEntry{mediaType, encode})
+}
+
+// Respond writes v in the representation that best matches the request's
+// Accept header. If no encoder is acceptable nothing is written and
+// ErrNotAcceptable is returned, for the caller to pass to Error or return
+// from an error handler. Without an Accept header v is written as JSON.
+func Respond(w http.ResponseWriter, r *http.Request, code int, v interface{}) error {
+
+	w.Header().Add("Vary", "Accept")
+
+	encodersMu.RLock()
+	offers := make([]string, len(encoders))
+	for i, e := range encoders {
+		offers[i] = e.mediaType
+	}
+	encodersMu.RUnlock()
+
+	i := Negotiate(r.Header.Get("Accept"), offers)
+	if i < 0 {
+		return ErrNotAcceptable
+	}
+
+	encodersMu.RLock()
+	encode := encoders[i].encode
+	encodersMu.RUnlock()
+
+	return encode(w, r, code, v)
+}
+
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// Negotiate returns the index of the offer that best matches the Accept
+// header value, or -1 if none is acceptable. An empty header accepts the
+// first offer. Offers with equal quality are ordered by their position in
+// the header and then by their position in offers.
+func Negotiate(accept string, offers []string) int {
+
+	if len(offers) == 0 {
+		return -1
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return 0
+	}
+
+	ranges := parseAccept(accept)
+
+	best, bestQ, bestPos := -1, 0.0, 0
+
+	for i, offer := range offers {
+
+		typ, subtype, _ := strings.Cut(offer, "/")
+
+		q, pos, specificity := 0.0, -1, -1
+
+		for j, mr := range ranges {
+			var s int
+
+			switch {
+			case mr.typ == typ && mr.subtype == subtype:
+				s = 2
+			case mr.typ == typ && mr.subtype == "*":
+				s = 1
+			case mr.typ == "*" && mr.subtype == "*":
+				s = 0
+			default:
+				continue
+			}
+
+			if s > specificity {
+				q, pos, specificity = mr.q, j, s
+			}
+		}
+
+		if q <= 0 {
+			continue
+		}
+
+		if best == -1 || q > bestQ || (q == bestQ && pos < bestPos) {
+			best, bestQ, bestPos = i, q, pos
+		}
+	}
+
+	return best
+}
+
+func parseAccept(accept string) []mediaRange {
+
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(accept, ",") {
+
+		params := strings.Split(part, ";")
+
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			if typ != "*" {
+				continue
+			}
+			subtype = "*"
+		}
+
+		mr := mediaRange{typ: typ, subtype: subtype, q: 1}
+
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+
+			if strings.EqualFold(name, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil && q >= 0 && q <= 1 {
+					mr.q = q
+				}
+			}
+		}
+
+		ranges = append(ranges, mr)
+	}
+
+	return ranges
+}
+
+// CSV writes v as RFC 4180 CSV. v may be a [][]string or a slice of structs,
+// in which case a header row is written from the `csv` tags or field names.
+func CSV(w http.ResponseWriter, r *http.Request, code int, v interface{}) error {
+
+	records, err := csvRecords(v)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+
+	cw := csv.NewWriter(&b)
+	cw.UseCRLF = true
+
+	if err := cw.WriteAll(records); err != nil {
+		return err
+	}
+
+	return write(w, r, code, "text/csv; charset=utf-8", []byte(b.String()))
+}
+
+func csvRecords(v interface{}) ([][]string, error) {
+
+	if records, ok := v.([][]string); ok {
+		return records, nil
+	}
+
+	rv := indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, errors.New("csv: value must be a slice")
+	}
+
+	et := indirectType(rv.Type().Elem())
+	if et.Kind() != reflect.Struct {
+		return nil, errors.New("csv: slice elements must be structs")
+	}
+
+	fields := csvFields(et)
+
+	header := make([]string, len(fields))
+	for i, f := range fields {
+		header[i] = f.name
+	}
+
+	records := [][]string{header}
+
+	for i := 0; i < rv.Len(); i++ {
+
+		ev := indirect(rv.Index(i))
+		record := make([]string, len(fields))
+
+		if ev.Kind() == reflect.Struct {
+			for j, f := range fields {
+				record[j] = fmt.Sprint(ev.Field(f.index).Interface())
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+type csvField struct {
+	index int
+	name  string
+}
+
+func csvFields(t reflect.Type) []csvField {
+
+	var fields []csvField
+
+	for i := 0; i < t.NumField(); i++ {
+
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(f.Tag.Get("csv"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fields = append(fields, csvField{index: i, name: name})
+	}
+
+	return fields
+}
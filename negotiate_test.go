@@ -0,0 +1,103 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+
+	offers := []string{"application/json", "application/xml", "text/csv", "text/plain"}
+
+	tests := []struct {
+		accept   string
+		expected int
+	}{
+		{"", 0},
+		{"*/*", 0},
+		{"text/csv", 2},
+		{"application/xml;q=0.5, text/*", 2},
+		{"text/*;q=0.9, text/plain", 3},
+		{"application/*;q=0.2, text/csv;q=0.1", 0},
+		{"text/csv;q=0, */*;q=0.1", 0},
+		{"image/png", -1},
+		{"*/*;q=0", -1},
+	}
+
+	for _, tt := range tests {
+		if i := Negotiate(tt.accept, offers); i != tt.expected {
+			t.Errorf("Negotiate(%q) = %d, expected %d", tt.accept, i, tt.expected)
+		}
+	}
+}
+
+func TestRespond_CSV(t *testing.T) {
+
+	type row struct {
+		ID   int    `csv:"id"`
+		Name string `csv:"name"`
+		Skip string `csv:"-"`
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/json;q=0.5, text/csv")
+	w := httptest.NewRecorder()
+
+	if err := Respond(w, req, http.StatusOK, []row{{1, "ada", ""}, {2, "grace, hopper", ""}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Error("unexpected content type: ", w.Header().Get("Content-Type"))
+	}
+
+	if w.Header().Get("Vary") != "Accept" {
+		t.Error("expected Vary: Accept")
+	}
+
+	if w.Body.String() != "id,name\r\n1,ada\r\n2,\"grace, hopper\"\r\n" {
+		t.Error("unexpected response body: ", w.Body.String())
+	}
+}
+
+func TestRespond_NotAcceptable(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "image/png")
+	w := httptest.NewRecorder()
+
+	err := Respond(w, req, http.StatusOK, "hello")
+	if err != ErrNotAcceptable {
+		t.Fatal("expected ErrNotAcceptable, got ", err)
+	}
+
+	if w.Body.Len() != 0 {
+		t.Fatal("expected nothing to be written, got ", w.Body.String())
+	}
+
+	Error(w, req, err)
+
+	if w.Code != http.StatusNotAcceptable {
+		t.Error("response code is not 406, but ", w.Code)
+	}
+}
+
+func TestRespond_CustomEncoder(t *testing.T) {
+
+	RegisterEncoder("application/vnd.test", func(w http.ResponseWriter, r *http.Request, code int, v interface{}) error {
+		return Text(w, r, code, "custom")
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/vnd.test")
+	w := httptest.NewRecorder()
+
+	if err := Respond(w, req, http.StatusOK, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Body.String() != "custom" {
+		t.Error("unexpected response body: ", w.Body.String())
+	}
+}
@@ -0,0 +1,204 @@
+package router
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrUnauthorized = NewHTTPError(http.StatusUnauthorized, "unauthorized", "authentication required")
+	ErrForbidden    = NewHTTPError(http.StatusForbidden, "forbidden", "forbidden")
+)
+
+// Rule decides whether the principal id satisfies a requirement. arg is the
+// part of the requirement after the rule name, such as "id" in "owner:id".
+type Rule func(r *http.Request, id *Identity, arg string) bool
+
+// Policy evaluates the requirements declared with Route.Require against the
+// principal of a request. A requirement is satisfied if
+//
+//   - it names a rule defined with Define, optionally followed by ":" and an
+//     argument, and the rule allows the request, or
+//   - the principal has it as a scope, or has a role granted it with Grant.
+//
+// Alternatives are separated by "|", so "users:delete|owner:id" lets admins
+// and the user themselves through. All requirements of a route must be
+// satisfied. Rules and grants must be set up before serving requests.
+//
+// NewPolicy defines the rules "authenticated", "role:<role>",
+// "scope:<scope>" and "owner:<param>", which matches the principal's
+// subject against a route param.
+//
+// The principal is set by authentication middleware, which must run before
+// the route. Anonymous requests are answered with 401 and a Bearer challenge
+// unless the middleware already added one; see Challenge.
+type Policy struct {
+	rules     map[string]Rule
+	grants    map[string]map[string]bool
+	challenge string
+}
+
+func NewPolicy() *Policy {
+
+	p := &Policy{
+		rules:     make(map[string]Rule),
+		grants:    make(map[string]map[string]bool),
+		challenge: "Bearer",
+	}
+
+	p.Define("authenticated", func(r *http.Request, id *Identity, arg string) bool {
+		return true
+	})
+
+	p.Define("role", func(r *http.Request, id *Identity, arg string) bool {
+		return id.HasRole(arg)
+	})
+
+	p.Define("scope", func(r *http.Request, id *Identity, arg string) bool {
+		return id.HasScope(arg)
+	})
+
+	p.Define("owner", func(r *http.Request, id *Identity, arg string) bool {
+		return id.Subject != "" && id.Subject == RouteParam(r, arg)
+	})
+
+	return p
+}
+
+func (p *Policy) Define(name string, rule Rule) {
+	p.rules[name] = rule
+}
+
+// Challenge sets the WWW-Authenticate challenge sent when an anonymous
+// request is rejected, such as `Basic realm="admin"`. An empty challenge
+// disables it.
+func (p *Policy) Challenge(challenge string) {
+	p.challenge = challenge
+}
+
+// Grant gives the permissions to every principal with role.
+func (p *Policy) Grant(role string, permissions ...string) {
+
+	granted, ok := p.grants[role]
+	if !ok {
+		granted = make(map[string]bool)
+		p.grants[role] = granted
+	}
+
+	for _, permission := range permissions {
+		granted[permission] = true
+	}
+}
+
+// Authorize returns nil if the principal of r satisfies all requirements,
+// ErrUnauthorized if r is anonymous and ErrForbidden otherwise.
+func (p *Policy) Authorize(r *http.Request, requirements []string) error {
+
+	id := Principal(r)
+	if id == nil {
+		return ErrUnauthorized
+	}
+
+	for _, requirement := range requirements {
+		if !p.satisfies(r, id, requirement) {
+			return ErrForbidden.Wrap(&requirementError{requirement})
+		}
+	}
+
+	return nil
+}
+
+func (p *Policy) satisfies(r *http.Request, id *Identity, requirement string) bool {
+
+	for _, alternative := range strings.Split(requirement, "|") {
+
+		alternative = strings.TrimSpace(alternative)
+
+		if rule, ok := p.rules[alternative]; ok {
+			if rule(r, id, "") {
+				return true
+			}
+			continue
+		}
+
+		if name, arg, ok := strings.Cut(alternative, ":"); ok {
+			if rule, ok := p.rules[name]; ok {
+				if rule(r, id, arg) {
+					return true
+				}
+				continue
+			}
+		}
+
+		if id.HasScope(alternative) {
+			return true
+		}
+
+		for _, role := range id.Roles {
+			if p.grants[role][alternative] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// RouteRequirements returns the requirements declared with Route.Require
+// for the route that matched r, or nil outside of a route.
+func RouteRequirements(r *http.Request) []string {
+
+	p, ok := r.Context().Value(contextKeyRoute).(*routeParams)
+	if !ok || p.node == nil {
+		return nil
+	}
+
+	return slices.Clone(p.node.lookupMeta(p.method).requirements)
+}
+
+type requirementError struct {
+	requirement string
+}
+
+func (e *requirementError) Error() string {
+	return "requirement not satisfied: " + e.requirement
+}
+
+// authorize wraps the handler of a route with requirements. Every decision
+// is logged with the request scoped logger.
+func (r *router) authorize(requirements []string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+
+		err := r.config.Policy.Authorize(req, requirements)
+
+		var subject string
+		if id := Principal(req); id != nil {
+			subject = id.Subject
+		}
+
+		level := slog.LevelDebug
+		if err != nil {
+			level = slog.LevelInfo
+		}
+
+		Log(req).LogAttrs(req.Context(), level, "authorization",
+			slog.String("method", req.Method),
+			slog.String("route", RoutePattern(req)),
+			slog.String("subject", subject),
+			slog.Any("requirements", requirements),
+			slog.Bool("allowed", err == nil))
+
+		if err != nil {
+			if err == ErrUnauthorized && r.config.Policy.challenge != "" && w.Header().Get("WWW-Authenticate") == "" {
+				w.Header().Set("WWW-Authenticate", r.config.Policy.challenge)
+			}
+
+			Error(w, req, err)
+			return
+		}
+
+		handler(w, req)
+	}
+}
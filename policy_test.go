@@ -0,0 +1,130 @@
+package router
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+
+	policy := NewPolicy()
+	policy.Grant("admin", "users:delete")
+
+	r := New(WithPolicy(policy))
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	r.Use(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		r = SetLogger(r, logger)
+
+		if sub := r.Header.Get("X-User"); sub != "" {
+			r = SetPrincipal(r, &Identity{Subject: sub, Roles: strings.Fields(r.Header.Get("X-Roles"))})
+		}
+
+		next(w, r)
+	})
+
+	r.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {})
+	r.Delete("/users/:id", func(w http.ResponseWriter, r *http.Request) {}).Require("users:delete|owner:id")
+
+	tests := []struct {
+		method string
+		user   string
+		roles  string
+		status int
+	}{
+		{"GET", "", "", http.StatusOK},
+		{"DELETE", "", "", http.StatusUnauthorized},
+		{"DELETE", "2", "", http.StatusForbidden},
+		{"DELETE", "1", "", http.StatusOK},
+		{"DELETE", "2", "admin", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest(tt.method, "/users/1", nil)
+		req.Header.Set("X-User", tt.user)
+		req.Header.Set("X-Roles", tt.roles)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s user=%q roles=%q: expected %d, got %d", tt.method, tt.user, tt.roles, tt.status, w.Code)
+		}
+
+		if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%s user=%q: expected a Bearer challenge, got %q", tt.method, tt.user, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+
+	if !strings.Contains(logs.String(), "allowed=false") || !strings.Contains(logs.String(), "route=/users/:id") {
+		t.Error("expected denied decisions to be logged, got ", logs.String())
+	}
+
+	for _, route := range r.GetRoutes() {
+
+		var expected []string
+		if route.Method == "DELETE" {
+			expected = []string{"users:delete|owner:id"}
+		}
+
+		if strings.Join(route.Requirements, ",") != strings.Join(expected, ",") {
+			t.Errorf("%s %s: expected requirements %v, got %v", route.Method, route.Path, expected, route.Requirements)
+		}
+
+		if len(route.Requirements) > 0 {
+			route.Requirements[0] = "modified"
+		}
+	}
+
+	for _, route := range r.GetRoutes() {
+		if route.Method == "DELETE" && route.Requirements[0] != "users:delete|owner:id" {
+			t.Error("GetRoutes must not expose the route's requirements for modification")
+		}
+	}
+}
+
+func TestRouteRequirements(t *testing.T) {
+
+	var requirements []string
+
+	r := New()
+
+	r.Use(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		requirements = RouteRequirements(r)
+		next(w, r)
+	})
+
+	r.Get("/reports", func(w http.ResponseWriter, r *http.Request) {}).Require("role:admin", "scope:reports")
+	r.Post("/reports", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		method   string
+		expected string
+	}{
+		{"GET", "role:admin,scope:reports"},
+		{"POST", ""},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest(tt.method, "/reports", nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		if strings.Join(requirements, ",") != tt.expected {
+			t.Errorf("%s: expected requirements %q, got %v", tt.method, tt.expected, requirements)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/reports", nil)
+
+	if RouteRequirements(req) != nil {
+		t.Error("expected no requirements outside of a route")
+	}
+}
@@ -0,0 +1,72 @@
+package router
+
+import (
+	"net/http"
+	"time"
+)
+
+var (
+	ErrPreconditionFailed   = NewHTTPError(http.StatusPreconditionFailed, "precondition_failed", "precondition failed")
+	ErrPreconditionRequired = NewHTTPError(http.StatusPreconditionRequired, "precondition_required", "request must be conditional")
+)
+
+// CheckPreconditions evaluates the conditional headers of r against the
+// current validators of the target resource in the order given by RFC 9110
+// section 13.2.2. Pass an empty etag if the resource does not exist, so
+// that If-None-Match: * allows it to be created. It reports whether the
+// request may proceed; if not, a 304 or 412 response has been written.
+func CheckPreconditions(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !ETagMatches(im, etag, true) {
+			Error(w, r, ErrPreconditionFailed)
+			return false
+		}
+	} else if ius := r.Header.Get("If-Unmodified-Since"); ius != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ius); err == nil && lastModified.Truncate(time.Second).After(t) {
+			Error(w, r, ErrPreconditionFailed)
+			return false
+		}
+	}
+
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+
+		if !ETagMatches(inm, etag, false) {
+			return true
+		}
+
+		if safe {
+			writeNotModified(w, etag)
+		} else {
+			Error(w, r, ErrPreconditionFailed)
+		}
+
+		return false
+	}
+
+	if safe && NotModified(r, etag, lastModified) {
+		writeNotModified(w, etag)
+		return false
+	}
+
+	return true
+}
+
+// IsConditional reports whether r carries a precondition that protects a
+// state changing request from overwriting a concurrent edit.
+func IsConditional(r *http.Request) bool {
+	return r.Header.Get("If-Match") != "" ||
+		r.Header.Get("If-Unmodified-Since") != "" ||
+		r.Header.Get("If-None-Match") != ""
+}
+
+func writeNotModified(w http.ResponseWriter, etag string) {
+
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+}
@@ -0,0 +1,47 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckPreconditions(t *testing.T) {
+
+	modified := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		method  string
+		header  string
+		value   string
+		etag    string
+		proceed bool
+		status  int
+	}{
+		{"PUT", "If-Match", `"v1"`, `"v1"`, true, http.StatusOK},
+		{"PUT", "If-Match", `"v0"`, `"v1"`, false, http.StatusPreconditionFailed},
+		{"PUT", "If-Match", `W/"v1"`, `"v1"`, false, http.StatusPreconditionFailed},
+		{"PUT", "If-Match", `*`, ``, false, http.StatusPreconditionFailed},
+		{"PUT", "If-None-Match", `*`, ``, true, http.StatusOK},
+		{"PUT", "If-None-Match", `*`, `"v1"`, false, http.StatusPreconditionFailed},
+		{"GET", "If-None-Match", `"v1"`, `"v1"`, false, http.StatusNotModified},
+		{"DELETE", "If-Unmodified-Since", modified.Format(http.TimeFormat), `"v1"`, true, http.StatusOK},
+		{"DELETE", "If-Unmodified-Since", modified.Add(-time.Hour).Format(http.TimeFormat), `"v1"`, false, http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest(tt.method, "/", nil)
+		req.Header.Set(tt.header, tt.value)
+		w := httptest.NewRecorder()
+
+		if got := CheckPreconditions(w, req, tt.etag, modified); got != tt.proceed {
+			t.Errorf("%s %s: %s: expected proceed %v, got %v", tt.method, tt.header, tt.value, tt.proceed, got)
+		}
+
+		if w.Code != tt.status {
+			t.Errorf("%s %s: %s: expected status %d, got %d", tt.method, tt.header, tt.value, tt.status, w.Code)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package router
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Identity is the authenticated principal of a request, set by the auth
+// middleware.
+type Identity struct {
+	// Subject identifies the principal, such as a user name or key ID.
+	Subject string
+
+	// Scheme is the authentication scheme that established the identity,
+	// such as "basic", "bearer" or "apikey".
+	Scheme string
+
+	Scopes     []string
+	Roles      []string
+	Attributes map[string]any
+}
+
+func (i *Identity) HasScope(scope string) bool {
+	return i != nil && slices.Contains(i.Scopes, scope)
+}
+
+func (i *Identity) HasRole(role string) bool {
+	return i != nil && slices.Contains(i.Roles, role)
+}
+
+var principalKey = NewKey[*Identity]("principal")
+
+// Principal returns the authenticated identity of r, or nil if the request
+// is anonymous.
+func Principal(r *http.Request) *Identity {
+	id, _ := principalKey.Get(r)
+	return id
+}
+
+// SetPrincipal returns a copy of r authenticated as id.
+func SetPrincipal(r *http.Request, id *Identity) *http.Request {
+	return principalKey.Set(r, id)
+}
@@ -0,0 +1,148 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ProblemDetails is an RFC 9457 problem details object. Extensions are
+// written as additional top level members.
+type ProblemDetails struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+func NewProblem(status int, detail string) *ProblemDetails {
+	return &ProblemDetails{
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (p *ProblemDetails) Error() string {
+
+	title := p.Title
+	if title == "" {
+		title = http.StatusText(p.Status)
+	}
+
+	if p.Detail != "" {
+		return title + ": " + p.Detail
+	}
+
+	return title
+}
+
+func (p *ProblemDetails) MarshalJSON() ([]byte, error) {
+
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+
+	m["type"] = p.Type
+	if p.Type == "" {
+		m["type"] = "about:blank"
+	}
+
+	m["title"] = p.Title
+	if p.Title == "" {
+		m["title"] = http.StatusText(p.Status)
+	}
+
+	m["status"] = p.Status
+
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+
+	return json.Marshal(m)
+}
+
+// Problem writes p as application/problem+json. A zero Status is sent as
+// 500 without modifying p.
+func Problem(w http.ResponseWriter, r *http.Request, p *ProblemDetails) error {
+
+	if p.Status == 0 {
+		c := *p
+		c.Status = http.StatusInternalServerError
+		p = &c
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return write(w, r, p.Status, "application/problem+json", body)
+}
+
+// ProblemErrorHandler is an ErrorHandler that renders every error as problem
+// details. *ProblemDetails are written as they are, *HTTPError and
+// ValidationErrors are converted and any other error becomes a 500 without
+// exposing its message.
+func ProblemErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	_ = Problem(w, r, toProblem(r, err))
+}
+
+func toProblem(r *http.Request, err error) *ProblemDetails {
+
+	var problem *ProblemDetails
+	if errors.As(err, &problem) {
+		return problem
+	}
+
+	p := &ProblemDetails{
+		Status:   http.StatusInternalServerError,
+		Instance: r.URL.Path,
+	}
+
+	var httpErr *HTTPError
+	var validationErrs ValidationErrors
+	var fieldErrs FieldErrors
+
+	if errors.As(err, &httpErr) {
+		p.Status = httpErr.Status
+		p.Detail = httpErr.Message
+
+		if httpErr.Code != "" {
+			p.Extensions = map[string]interface{}{"code": httpErr.Code}
+		}
+
+		if errors.As(err, &fieldErrs) {
+			p.setExtension("errors", fieldErrs)
+		}
+	} else if errors.As(err, &validationErrs) {
+		p.Status = http.StatusUnprocessableEntity
+		p.Detail = "request validation failed"
+		p.setExtension("errors", validationErrs)
+	}
+
+	return p
+}
+
+func (p *ProblemDetails) setExtension(name string, value interface{}) {
+
+	if p.Extensions == nil {
+		p.Extensions = map[string]interface{}{}
+	}
+
+	p.Extensions[name] = value
+}
+
+func statusProblem(r *http.Request, status int) *ProblemDetails {
+	return &ProblemDetails{
+		Status:   status,
+		Instance: r.URL.Path,
+	}
+}
@@ -0,0 +1,117 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProblem(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/accounts/1", nil)
+	w := httptest.NewRecorder()
+
+	p := &ProblemDetails{
+		Type:       "https://example.com/probs/out-of-credit",
+		Title:      "You do not have enough credit.",
+		Status:     http.StatusForbidden,
+		Detail:     "Your current balance is 30, but that costs 50.",
+		Instance:   "/account/12345/msgs/abc",
+		Extensions: map[string]interface{}{"balance": 30, "status": 200},
+	}
+
+	if err := Problem(w, req, p); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Code != http.StatusForbidden {
+		t.Error("response code is not 403, but ", w.Code)
+	}
+
+	if w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Error("unexpected content type: ", w.Header().Get("Content-Type"))
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	if body["status"] != float64(403) || body["balance"] != float64(30) || body["type"] != p.Type {
+		t.Error("unexpected problem body: ", body)
+	}
+}
+
+func TestRouter_WithProblemDetails(t *testing.T) {
+
+	r := New(WithProblemDetails())
+
+	r.Get("/users/:id", Handle(func(w http.ResponseWriter, r *http.Request) error {
+		return NewHTTPError(http.StatusNotFound, "user_not_found", "user does not exist")
+	}))
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"GET", "/missing", http.StatusNotFound, `{"instance":"/missing","status":404,"title":"Not Found","type":"about:blank"}`},
+		{"POST", "/users/1", http.StatusMethodNotAllowed, `{"instance":"/users/1","status":405,"title":"Method Not Allowed","type":"about:blank"}`},
+		{"GET", "/users/1", http.StatusNotFound, `{"code":"user_not_found","detail":"user does not exist","instance":"/users/1","status":404,"title":"Not Found","type":"about:blank"}`},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest(tt.method, tt.path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.status, w.Code)
+		}
+
+		if w.Body.String() != tt.body {
+			t.Errorf("%s %s: unexpected body %s", tt.method, tt.path, w.Body.String())
+		}
+	}
+}
+
+func TestProblem_ZeroStatus(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	p := &ProblemDetails{Detail: "something broke"}
+
+	if err := Problem(w, req, p); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Code != http.StatusInternalServerError {
+		t.Error("response code is not 500, but ", w.Code)
+	}
+
+	if p.Status != 0 {
+		t.Error("expected the caller's problem to be left unchanged, got status ", p.Status)
+	}
+}
+
+func TestDefaultErrorHandler_Problem(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+
+	DefaultErrorHandler(w, req, NewProblem(http.StatusConflict, "version mismatch"))
+
+	if w.Code != http.StatusConflict {
+		t.Error("response code is not 409, but ", w.Code)
+	}
+
+	if w.Body.String() != `{"status":409,"message":"version mismatch"}` {
+		t.Error("unexpected response body: ", w.Body.String())
+	}
+}
@@ -20,3 +20,46 @@ func RouteParam(r *http.Request, name string) string {
 
 	return ""
 }
+
+// MountPrefix returns the part of the original request path that was stripped
+// by Mount before the request reached the mounted handler.
+func MountPrefix(r *http.Request) string {
+
+	if prefix, ok := r.Context().Value(contextKeyMount).(string); ok {
+		return prefix
+	}
+
+	return ""
+}
+
+// RoutePattern returns the pattern of the route that matched r, such as
+// "/users/:id", or an empty string outside of a route.
+func RoutePattern(r *http.Request) string {
+
+	p, ok := r.Context().Value(contextKeyRoute).(*routeParams)
+	if !ok || p.node == nil {
+		return ""
+	}
+
+	if pattern := p.node.getPath(); pattern != "" {
+		return pattern
+	}
+
+	return "/"
+}
+
+// RouteParams returns all path parameters of the route that matched r.
+func RouteParams(r *http.Request) map[string]string {
+
+	p, ok := r.Context().Value(contextKeyRoute).(*routeParams)
+	if !ok {
+		return nil
+	}
+
+	params := make(map[string]string, len(p.Keys))
+	for i, k := range p.Keys {
+		params[k] = p.Values[i]
+	}
+
+	return params
+}
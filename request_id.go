@@ -0,0 +1,23 @@
+package router
+
+import (
+	"context"
+	"net/http"
+)
+
+var requestIDKey = NewKey[string]("request_id")
+
+// RequestID returns the ID assigned to r by middleware.RequestID.
+func RequestID(r *http.Request) string {
+	return RequestIDFromContext(r.Context())
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := requestIDKey.Value(ctx)
+	return id
+}
+
+// SetRequestID returns a copy of r with the given request ID.
+func SetRequestID(r *http.Request, id string) *http.Request {
+	return requestIDKey.Set(r, id)
+}
@@ -2,20 +2,216 @@ package router
 
 import (
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
-func JSON(w http.ResponseWriter, code int, data interface{}) error {
+func JSON(w http.ResponseWriter, r *http.Request, code int, data interface{}) error {
 
 	jsonBytes, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
+	return write(w, r, code, "application/json", jsonBytes)
+}
+
+func XML(w http.ResponseWriter, r *http.Request, code int, data interface{}) error {
+
+	xmlBytes, err := xml.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return write(w, r, code, "application/xml; charset=utf-8", append([]byte(xml.Header), xmlBytes...))
+}
+
+func Text(w http.ResponseWriter, r *http.Request, code int, text string) error {
+	return write(w, r, code, "text/plain; charset=utf-8", []byte(text))
+}
 
-	w.Write(jsonBytes)
+func HTML(w http.ResponseWriter, r *http.Request, code int, html string) error {
+	return write(w, r, code, "text/html; charset=utf-8", []byte(html))
+}
+
+func NoContent(w http.ResponseWriter, r *http.Request) error {
+
+	if err := r.Context().Err(); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 
 	return nil
 }
+
+// Created writes data as JSON with status 201 and a Location header. Use
+// BuildPath to build location from a route pattern.
+func Created(w http.ResponseWriter, r *http.Request, location string, data interface{}) error {
+
+	if err := r.Context().Err(); err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", location)
+
+	if data == nil {
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}
+
+	return JSON(w, r, http.StatusCreated, data)
+}
+
+func Redirect(w http.ResponseWriter, r *http.Request, code int, location string) error {
+
+	if code < 300 || code > 399 {
+		return fmt.Errorf("invalid redirect status code %d", code)
+	}
+
+	if err := r.Context().Err(); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, location, code)
+
+	return nil
+}
+
+// Attachment writes content as a file download named filename. The
+// Content-Length is set when content is an io.Seeker, counting from its
+// current offset.
+func Attachment(w http.ResponseWriter, r *http.Request, filename, contentType string, content io.Reader) error {
+
+	if err := r.Context().Err(); err != nil {
+		return err
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", ContentDisposition("attachment", filename))
+
+	if s, ok := content.(io.Seeker); ok {
+		offset, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+
+		end, err := s.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Length", strconv.FormatInt(end-offset, 10))
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	_, err := io.Copy(w, content)
+
+	return err
+}
+
+// ContentDisposition formats a Content-Disposition header value as described
+// in RFC 6266. Non-ASCII filenames are sent in the filename* parameter with
+// an ASCII approximation in filename for older clients.
+func ContentDisposition(disposition, filename string) string {
+
+	fallback := make([]byte, 0, len(filename))
+	ascii := true
+
+	for _, c := range filename {
+		switch {
+		case c > 0x7e || c < 0x20:
+			ascii = false
+			fallback = append(fallback, '_')
+		case c == '"' || c == '\\' || c == '/':
+			fallback = append(fallback, '_')
+		default:
+			fallback = append(fallback, byte(c))
+		}
+	}
+
+	v := disposition + `; filename="` + string(fallback) + `"`
+
+	if !ascii {
+		v += "; filename*=UTF-8''" + encodeExtValue(filename)
+	}
+
+	return v
+}
+
+// encodeExtValue percent-encodes s as an RFC 8187 value-chars production.
+func encodeExtValue(s string) string {
+
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+			strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0x0f])
+	}
+
+	return b.String()
+}
+
+// BuildPath replaces the params in a route pattern such as "/users/:id" with
+// the given name, value pairs. Values are path escaped.
+func BuildPath(pattern string, params ...string) string {
+
+	segments := strings.Split(pattern, "/")
+
+	for i, segment := range segments {
+		if len(segment) == 0 || segment[0] != ':' {
+			continue
+		}
+
+		for j := 0; j+1 < len(params); j += 2 {
+			if params[j] == segment[1:] {
+				segments[i] = url.PathEscape(params[j+1])
+				break
+			}
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// write sends body unless the client has gone away, in which case the
+// context error is returned and nothing is written.
+func write(w http.ResponseWriter, r *http.Request, code int, contentType string, body []byte) error {
+
+	if err := r.Context().Err(); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(code)
+
+	_, err := w.Write(body)
+
+	return err
+}
@@ -0,0 +1,131 @@
+package router
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	if err := JSON(w, req, http.StatusOK, map[string]string{"a": "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Header().Get("Content-Length") != "9" {
+		t.Error("unexpected content length: ", w.Header().Get("Content-Length"))
+	}
+
+	if w.Body.String() != `{"a":"b"}` {
+		t.Error("unexpected response body: ", w.Body.String())
+	}
+}
+
+func TestXML(t *testing.T) {
+
+	type user struct {
+		Name string `xml:"name"`
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	if err := XML(w, req, http.StatusOK, user{Name: "ada"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(w.Body.String(), "<user><name>ada</name></user>") {
+		t.Error("unexpected response body: ", w.Body.String())
+	}
+}
+
+func TestCreated(t *testing.T) {
+
+	req, _ := http.NewRequest("POST", "/", nil)
+	w := httptest.NewRecorder()
+
+	if err := Created(w, req, BuildPath("/users/:id/files/:name", "id", "42", "name", "a b"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Code != http.StatusCreated {
+		t.Error("response code is not 201, but ", w.Code)
+	}
+
+	if w.Header().Get("Location") != "/users/42/files/a%20b" {
+		t.Error("unexpected location: ", w.Header().Get("Location"))
+	}
+}
+
+func TestAttachment(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	if err := Attachment(w, req, "résumé \"final\".txt", "text/plain", strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `attachment; filename="r_sum_ _final_.txt"; filename*=UTF-8''r%C3%A9sum%C3%A9%20%22final%22.txt`
+	if w.Header().Get("Content-Disposition") != expected {
+		t.Error("unexpected content disposition: ", w.Header().Get("Content-Disposition"))
+	}
+
+	if w.Header().Get("Content-Length") != "5" {
+		t.Error("unexpected content length: ", w.Header().Get("Content-Length"))
+	}
+}
+
+func TestAttachment_PartiallyRead(t *testing.T) {
+
+	content := strings.NewReader("hello world")
+	_, _ = content.Seek(6, io.SeekStart)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	if err := Attachment(w, req, "world.txt", "text/plain", content); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Header().Get("Content-Length") != "5" {
+		t.Error("unexpected content length: ", w.Header().Get("Content-Length"))
+	}
+
+	if w.Body.String() != "world" {
+		t.Error("unexpected response body: ", w.Body.String())
+	}
+}
+
+func TestJSON_ClientGone(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	if err := JSON(w, req, http.StatusOK, map[string]string{"a": "b"}); err != context.Canceled {
+		t.Error("expected context.Canceled, got ", err)
+	}
+
+	if w.Body.Len() != 0 {
+		t.Error("unexpected response body: ", w.Body.String())
+	}
+}
+
+func TestRedirect_InvalidCode(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	if err := Redirect(w, req, http.StatusOK, "/login"); err == nil {
+		t.Error("expected error for non-3xx status code")
+	}
+}
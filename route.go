@@ -1,8 +1,54 @@
 package router
 
+import (
+	"net/http"
+	"reflect"
+)
+
+type routeMeta struct {
+	input        reflect.Type
+	output       reflect.Type
+	group        *router
+	middleware   []Middleware
+	requirements []string
+}
+
+// route is a single method of a node. It is returned by the route methods so
+// that middleware added with Use only runs for that method.
+type route struct {
+	node   *routeTreeNode
+	method uint8
+}
+
+func (r route) Use(m ...Middleware) {
+	meta := r.node.getMeta(r.method)
+	meta.middleware = append(meta.middleware, m...)
+}
+
+func (r route) Require(requirements ...string) {
+	meta := r.node.getMeta(r.method)
+	meta.requirements = append(meta.requirements, requirements...)
+}
+
 type routeParams struct {
 	Keys   []string
 	Values []string
+	node   *routeTreeNode
+	method uint8
+	before []func(w http.ResponseWriter, r *http.Request) bool
+}
+
+// runBefore runs the functions registered with BeforeHandler and reports
+// whether the handler may run.
+func (r *routeParams) runBefore(w http.ResponseWriter, req *http.Request) bool {
+
+	for _, fn := range r.before {
+		if !fn(w, req) {
+			return false
+		}
+	}
+
+	return true
 }
 
 func (r *routeParams) get(key string) string {
@@ -25,24 +25,24 @@ const (
 )
 
 type routeTreeNode struct {
-	segment    string
-	parent     *routeTreeNode
-	children   []*routeTreeNode
-	middleware []Middleware
-	handlers   []http.HandlerFunc
-	param      bool
-	catchAll   bool
+	segment  string
+	parent   *routeTreeNode
+	children []*routeTreeNode
+	handlers []http.HandlerFunc
+	meta     []routeMeta
+	param    bool
+	catchAll bool
 }
 
 func newRouteTreeNode() *routeTreeNode {
 	return &routeTreeNode{
-		segment:    "",
-		parent:     nil,
-		children:   nil,
-		middleware: nil,
-		handlers:   nil,
-		param:      false,
-		catchAll:   false,
+		segment:  "",
+		parent:   nil,
+		children: nil,
+		handlers: nil,
+		meta:     nil,
+		param:    false,
+		catchAll: false,
 	}
 }
 
@@ -208,25 +208,74 @@ func (r *routeTreeNode) SetHandler(method string, handler http.HandlerFunc) {
 }
 
 func (r *routeTreeNode) GetHandler(method string) http.HandlerFunc {
+	handler, _ := r.findHandler(method)
+	return handler
+}
+
+// findHandler returns the handler for method and the method slot it was
+// registered under, which is httpMethodAny for catch-all method routes.
+func (r *routeTreeNode) findHandler(method string) (http.HandlerFunc, uint8) {
 
 	if r.handlers == nil {
-		return nil
+		return nil, 0
 	}
 
 	if r.handlers[httpMethodAny] != nil {
-		return r.handlers[httpMethodAny]
+		return r.handlers[httpMethodAny], httpMethodAny
+	}
+
+	m := methodToUint8(method)
+	if m == httpMethodCount {
+		return nil, 0
+	}
+
+	return r.handlers[m], m
+}
+
+// getMeta returns the metadata of method for modification, allocating it
+// on first use. It must only be called while registering routes.
+func (r *routeTreeNode) getMeta(method uint8) *routeMeta {
+	if r.meta == nil {
+		r.meta = make([]routeMeta, httpMethodCount)
+	}
+
+	return &r.meta[method]
+}
+
+// lookupMeta returns the metadata of method, or the zero value if none was
+// set. Unlike getMeta it never modifies r, so it is safe while serving.
+func (r *routeTreeNode) lookupMeta(method uint8) routeMeta {
+	if r.meta == nil {
+		return routeMeta{}
 	}
 
-	return r.handlers[methodToUint8(method)]
+	return r.meta[method]
 }
 
-func (r *routeTreeNode) Use(m ...Middleware) {
+// middlewareChain returns the middleware of the groups the route of method
+// was registered through, outermost first, followed by the middleware of
+// the route itself. The slice of a single level is returned as is to avoid
+// allocating on every request.
+func (r *routeTreeNode) middlewareChain(method uint8) []Middleware {
+
+	meta := r.lookupMeta(method)
+	chain := meta.middleware
+
+	for group := meta.group; group != nil; group = group.parent {
+
+		if len(group.middleware) == 0 {
+			continue
+		}
+
+		if len(chain) == 0 {
+			chain = group.middleware
+			continue
+		}
 
-	if r.middleware == nil {
-		r.middleware = make([]Middleware, 0)
+		chain = append(group.middleware[:len(group.middleware):len(group.middleware)], chain...)
 	}
 
-	r.middleware = append(r.middleware, m...)
+	return chain
 }
 
 func (r *routeTreeNode) getPath() string {
@@ -263,7 +312,9 @@ func methodToUint8(method string) uint8 {
 		return httpMethodAny
 	}
 
-	return httpMethodGet
+	// Unsupported methods map to a slot past the end so that they never
+	// match a registered handler.
+	return httpMethodCount
 }
 
 func uint8ToMethod(method uint8) string {
@@ -3,17 +3,29 @@ package router
 import (
 	"context"
 	"net/http"
+	"reflect"
+	"slices"
+
+	"github.com/ironfang-ltd/router-go/ws"
 )
 
 const (
 	ErrPathMustStartWithSlash  = "path must start with '/'"
 	ErrPathMustNotEndWithSlash = "path must not end with '/'"
+	ErrUnsupportedMethod       = "unsupported method"
 )
 
 type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
 
 type Route interface {
 	Use(middleware ...Middleware)
+
+	// Require declares requirements the principal of a request must meet
+	// before the handler runs. They are evaluated by the router's Policy
+	// after all middleware, so authentication middleware runs first.
+	// Middleware that responds without calling next therefore bypasses
+	// them; such middleware should check RouteRequirements, as Cache does.
+	Require(requirements ...string)
 }
 
 type Router interface {
@@ -22,9 +34,16 @@ type Router interface {
 	Put(path string, handler http.HandlerFunc) Route
 	Patch(path string, handler http.HandlerFunc) Route
 	Delete(path string, handler http.HandlerFunc) Route
+	Method(method, path string, handler http.Handler) Route
 	Group(prefix string) Group
 	Static(path, dir string) Route
+	Mount(prefix string, handler http.Handler) Route
+	WebSocket(path string, handler func(conn *ws.Conn), opts ...WebSocketOption) Route
+
+	// Use adds middleware that runs for every request, including requests
+	// that match no route and get 404 or 405.
 	Use(middleware ...Middleware)
+
 	ServeHTTP(w http.ResponseWriter, r *http.Request)
 	GetRoutes() []RouteDescriptor
 }
@@ -35,21 +54,28 @@ type Group interface {
 	Put(path string, handler http.HandlerFunc) Route
 	Patch(path string, handler http.HandlerFunc) Route
 	Delete(path string, handler http.HandlerFunc) Route
+	Method(method, path string, handler http.Handler) Route
 	Group(prefix string) Group
 	Static(path, dir string) Route
+	Mount(prefix string, handler http.Handler) Route
+	WebSocket(path string, handler func(conn *ws.Conn), opts ...WebSocketOption) Route
 	Use(middleware ...Middleware)
 }
 
 type RouteDescriptor struct {
-	Method string
-	Path   string
+	Method       string
+	Path         string
+	Input        reflect.Type
+	Output       reflect.Type
+	Requirements []string
 }
 
 type router struct {
-	parent *router
-	prefix string
-	node   *routeTreeNode
-	config *Config
+	parent     *router
+	prefix     string
+	node       *routeTreeNode
+	config     *Config
+	middleware []Middleware
 }
 
 func New(opts ...Option) Router {
@@ -57,12 +83,23 @@ func New(opts ...Option) Router {
 	config := &Config{
 		NotFoundHandler:         nil,
 		MethodNotAllowedHandler: nil,
+		ErrorHandler:            nil,
+		ProblemDetails:          false,
+		Policy:                  nil,
 	}
 
 	for _, opt := range opts {
 		opt(config)
 	}
 
+	if config.ProblemDetails && config.ErrorHandler == nil {
+		config.ErrorHandler = ProblemErrorHandler
+	}
+
+	if config.Policy == nil {
+		config.Policy = NewPolicy()
+	}
+
 	r := router{
 		parent: nil,
 		prefix: "",
@@ -93,15 +130,30 @@ func (r *router) Delete(path string, handler http.HandlerFunc) Route {
 	return r.mapMethod(http.MethodDelete, path, handler)
 }
 
+func (r *router) Method(method, path string, handler http.Handler) Route {
+
+	route := r.mapMethod(method, path, handler.ServeHTTP)
+
+	if t, ok := handler.(typedHandler); ok {
+		meta := route.node.getMeta(route.method)
+		meta.input, meta.output = t.types()
+	}
+
+	return route
+}
+
 func (r *router) Any(path string, handler http.HandlerFunc) Route {
 	return r.mapMethod("*", path, handler)
 }
 
+// Group returns a Group for registering routes under prefix. Middleware
+// added to the group runs only for the routes registered through it, not for
+// other routes that happen to share the prefix.
 func (r *router) Group(prefix string) Group {
 	group := router{
 		parent: r,
 		prefix: prefix,
-		node:   newRouteTreeNode(),
+		node:   r.node,
 	}
 
 	return &group
@@ -110,34 +162,55 @@ func (r *router) Group(prefix string) Group {
 func (r *router) Static(path, dir string) Route {
 	return r.mapMethod(
 		http.MethodGet,
-		r.node.getPath()+path+"*",
-		StaticFileHandler(r.node.getPath()+path, dir))
+		path+"*",
+		StaticFileHandler(r.getPrefix()+path, dir))
 }
 
 func (r *router) Use(m ...Middleware) {
-	r.node.Use(m...)
+	r.middleware = append(r.middleware, m...)
 }
 
 func (r *router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 	node, params := r.node.Find(req.URL.Path)
 	if node == nil {
-		r.notFound(w, req)
+		r.handleUnmatched(w, req, r.notFound)
 		return
 	}
 
-	handler := node.GetHandler(req.Method)
+	handler, method := node.findHandler(req.Method)
 	if handler == nil {
-		r.methodNotAllowed(w, req)
+		r.handleUnmatched(w, req, r.methodNotAllowed)
 		return
 	}
 
-	if params != nil {
-		ctx := context.WithValue(req.Context(), contextKeyRoute, params)
-		req = req.WithContext(ctx)
+	if node.meta != nil && len(node.meta[method].requirements) > 0 {
+		handler = r.authorize(node.meta[method].requirements, handler)
+	}
+
+	if params == nil {
+		params = &routeParams{}
+	}
+
+	params.node = node
+	params.method = method
+
+	ctx := context.WithValue(req.Context(), contextKeyRoute, params)
+
+	if r.config.ErrorHandler != nil {
+		ctx = context.WithValue(ctx, contextKeyError, r.config.ErrorHandler)
+	}
+
+	req = req.WithContext(ctx)
+
+	mc := middlewareContext{
+		current:    0,
+		middleware: node.middlewareChain(method),
+		params:     params,
+		final:      handler,
 	}
 
-	r.handleMiddleware(node, w, req, handler)
+	mc.Next(w, req)
 }
 
 func (r *router) GetRoutes() []RouteDescriptor {
@@ -166,9 +239,14 @@ func (r *router) GetRoutes() []RouteDescriptor {
 					p = "/"
 				}
 
+				meta := node.lookupMeta(uint8(i))
+
 				routes = append(routes, RouteDescriptor{
-					Method: uint8ToMethod(uint8(i)),
-					Path:   p,
+					Method:       uint8ToMethod(uint8(i)),
+					Path:         p,
+					Input:        meta.input,
+					Output:       meta.output,
+					Requirements: slices.Clone(meta.requirements),
 				})
 			}
 		}
@@ -179,15 +257,9 @@ func (r *router) GetRoutes() []RouteDescriptor {
 	return routes
 }
 
-func (r *router) handleMiddleware(n *routeTreeNode, w http.ResponseWriter, req *http.Request, final http.HandlerFunc) {
-
-	if n.parent != nil {
-		r.handleMiddleware(n.parent, w, req, final)
-
-		return
-	}
+func (r *router) handleMiddleware(middleware []Middleware, w http.ResponseWriter, req *http.Request, final http.HandlerFunc) {
 
-	if n.middleware == nil {
+	if middleware == nil {
 		final(w, req)
 
 		return
@@ -195,18 +267,16 @@ func (r *router) handleMiddleware(n *routeTreeNode, w http.ResponseWriter, req *
 
 	mc := middlewareContext{
 		current:    0,
-		middleware: n.middleware,
+		middleware: middleware,
 		final:      final,
 	}
 
 	mc.Next(w, req)
 }
 
-func (r *router) mapMethod(method, path string, handler http.HandlerFunc) *routeTreeNode {
+func (r *router) mapMethod(method, path string, handler http.HandlerFunc) route {
 
-	if r.parent != nil {
-		return r.parent.mapMethod(method, r.prefix+path, handler)
-	}
+	path = r.getPrefix() + path
 
 	if len(path) == 0 || path[0] != PathSep {
 		panic(ErrPathMustStartWithSlash)
@@ -216,9 +286,31 @@ func (r *router) mapMethod(method, path string, handler http.HandlerFunc) *route
 		panic(ErrPathMustNotEndWithSlash)
 	}
 
+	if methodToUint8(method) == httpMethodCount {
+		panic(ErrUnsupportedMethod)
+	}
+
 	node := r.node.GetOrCreateNode(path)
 	node.SetHandler(method, handler)
-	return node
+
+	// Registering a route again replaces it, including the middleware added
+	// to the previous registration.
+	route := route{node: node, method: methodToUint8(method)}
+	*route.node.getMeta(route.method) = routeMeta{group: r}
+
+	return route
+}
+
+// handleUnmatched runs the middleware added with Use on the router before
+// final, so that logging, request IDs and the like also cover requests that
+// match no route. Group and route middleware does not run.
+func (r *router) handleUnmatched(w http.ResponseWriter, req *http.Request, final http.HandlerFunc) {
+
+	if r.config.ErrorHandler != nil {
+		req = req.WithContext(context.WithValue(req.Context(), contextKeyError, r.config.ErrorHandler))
+	}
+
+	r.handleMiddleware(r.middleware, w, req, final)
 }
 
 func (r *router) methodNotAllowed(w http.ResponseWriter, req *http.Request) {
@@ -229,6 +321,12 @@ func (r *router) methodNotAllowed(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if r.config.ProblemDetails {
+		_ = Problem(w, req, statusProblem(req, http.StatusMethodNotAllowed))
+
+		return
+	}
+
 	w.WriteHeader(http.StatusMethodNotAllowed)
 }
 
@@ -240,5 +338,11 @@ func (r *router) notFound(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if r.config.ProblemDetails {
+		_ = Problem(w, req, statusProblem(req, http.StatusNotFound))
+
+		return
+	}
+
 	w.WriteHeader(http.StatusNotFound)
 }
@@ -1,9 +1,13 @@
 package router
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/ironfang-ltd/router-go/ws"
 )
 
 func TestRouter_GetWithParam(t *testing.T) {
@@ -103,6 +107,193 @@ func TestRouter_NodeOrder(t *testing.T) {
 	}
 }
 
+func TestRouter_Mount(t *testing.T) {
+
+	sub := New()
+
+	sub.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(MountPrefix(r) + " " + r.URL.Path + " " + RouteParam(r, "id")))
+	})
+
+	r := New()
+
+	r.Use(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		w.Header().Set("X-Middleware", "true")
+		next(w, r)
+	})
+
+	r.Mount("/admin", sub)
+
+	req, _ := http.NewRequest("GET", "/admin/users/42", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatal("response code is not 200, but ", w.Code)
+	}
+
+	if w.Body.String() != "/admin /users/42 42" {
+		t.Error("unexpected response body: ", w.Body.String())
+	}
+
+	if w.Header().Get("X-Middleware") != "true" {
+		t.Error("router middleware was not applied to mounted handler")
+	}
+}
+
+func TestRouter_MountInGroup(t *testing.T) {
+
+	var path string
+
+	r := New()
+
+	r.Group("/api").Mount("/docs", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+	}))
+
+	for _, p := range []string{"/api/docs", "/api/docs/"} {
+
+		req, _ := http.NewRequest("POST", p, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatal("response code is not 200, but ", w.Code)
+		}
+
+		if path != "/" {
+			t.Errorf("expected path '/' for %s, got '%s'", p, path)
+		}
+	}
+}
+
+func TestRouter_UnsupportedMethod(t *testing.T) {
+
+	r := New()
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	req, _ := http.NewRequest("PURGE", "/", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Error("response code is not 405, but ", w.Code)
+	}
+
+	defer func() {
+		if recover() != ErrUnsupportedMethod {
+			t.Error("expected registering an unsupported method to panic")
+		}
+	}()
+
+	r.Method("PURGE", "/cache", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+}
+
+func TestRouter_GetRoutesWhileServing(t *testing.T) {
+
+	r := New()
+
+	r.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {})
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 100; i++ {
+			req, _ := http.NewRequest("GET", "/users/1", nil)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		if routes := r.GetRoutes(); len(routes) != 1 {
+			t.Fatal("expected 1 route, got ", len(routes))
+		}
+	}
+
+	<-done
+}
+
+func TestRouter_WebSocket(t *testing.T) {
+
+	r := New()
+
+	r.WebSocket("/ws", func(conn *ws.Conn) {
+		mt, data, err := conn.ReadMessage()
+		if err == nil {
+			_ = conn.WriteMessage(mt, data)
+		}
+	}).Use(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if r.URL.Query().Get("token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	})
+
+	s := httptest.NewServer(r)
+	defer s.Close()
+
+	url := "ws" + strings.TrimPrefix(s.URL, "http") + "/ws"
+
+	_, res, err := ws.Dial(context.Background(), url, nil)
+	if err == nil || res.StatusCode != http.StatusUnauthorized {
+		t.Fatal("expected middleware to reject the upgrade")
+	}
+
+	conn, _, err := ws.Dial(context.Background(), url+"?token=secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	_ = conn.WriteMessage(ws.TextMessage, []byte("hello"))
+
+	_, data, err := conn.ReadMessage()
+	if err != nil || string(data) != "hello" {
+		t.Fatal("unexpected echo: ", string(data), err)
+	}
+}
+
+func TestRouter_WebSocketOptions(t *testing.T) {
+
+	r := New()
+
+	r.WebSocket("/ws", func(conn *ws.Conn) {},
+		WithCheckOrigin(func(r *http.Request) bool { return false }))
+
+	r.WebSocket("/chat", func(conn *ws.Conn) {},
+		WithSubprotocols("chat.v2", "chat.v1"))
+
+	s := httptest.NewServer(r)
+	defer s.Close()
+
+	url := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	_, res, err := ws.Dial(context.Background(), url+"/ws", nil)
+	if err == nil || res.StatusCode != http.StatusForbidden {
+		t.Fatal("expected the upgrader to reject the origin")
+	}
+
+	conn, res, err := ws.Dial(context.Background(), url+"/chat", http.Header{
+		"Sec-WebSocket-Protocol": {"chat.v1, chat.v2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	if p := res.Header.Get("Sec-WebSocket-Protocol"); p != "chat.v2" {
+		t.Error("expected subprotocol chat.v2, got ", p)
+	}
+}
+
 func BenchmarkGet(b *testing.B) {
 
 	req, _ := http.NewRequest("GET", "/", nil)
@@ -132,3 +323,148 @@ func BenchmarkGetWithParam(b *testing.B) {
 		r.ServeHTTP(w, req)
 	}
 }
+
+func TestRouter_MiddlewareOrder(t *testing.T) {
+
+	var calls []string
+
+	record := func(name string) Middleware {
+		return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+			calls = append(calls, name)
+			next(w, r)
+		}
+	}
+
+	r := New()
+	r.Use(record("root"))
+
+	api := r.Group("/api")
+	api.Use(record("group"))
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}
+
+	api.Get("/users", handler).Use(record("route"))
+	api.Post("/users", handler)
+	api.Get("/users/:id", handler)
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/api/users", "root group route handler"},
+		{"POST", "/api/users", "root group handler"},
+		{"GET", "/api/users/1", "root group handler"},
+	}
+
+	for _, tt := range tests {
+
+		calls = nil
+
+		req, _ := http.NewRequest(tt.method, tt.path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		if got := strings.Join(calls, " "); got != tt.want {
+			t.Errorf("%s %s: expected '%s', got '%s'", tt.method, tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestRouter_BeforeHandler(t *testing.T) {
+
+	var calls []string
+
+	r := New()
+
+	r.Use(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		BeforeHandler(r, func(w http.ResponseWriter, r *http.Request) bool {
+			calls = append(calls, "before")
+			w.WriteHeader(http.StatusForbidden)
+			return false
+		})
+		next(w, r)
+		calls = append(calls, "after")
+	})
+
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}).Use(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		calls = append(calls, "route")
+		next(w, r)
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Error("response code is not 403, but ", w.Code)
+	}
+
+	if got := strings.Join(calls, " "); got != "route before after" {
+		t.Error("unexpected calls: ", got)
+	}
+
+	if BeforeHandler(req, func(w http.ResponseWriter, r *http.Request) bool { return true }) {
+		t.Error("expected BeforeHandler to report a request outside of a route")
+	}
+}
+
+func TestRouter_GroupMiddlewareScope(t *testing.T) {
+
+	r := New()
+
+	admin := r.Group("/api")
+	admin.Use(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	admin.Get("/admin", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.Get("/api/health", func(w http.ResponseWriter, r *http.Request) {})
+	r.Group("/api").Get("/public", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/api/admin", http.StatusUnauthorized},
+		{"/api/health", http.StatusOK},
+		{"/api/public", http.StatusOK},
+		{"/api/missing", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.code, w.Code)
+		}
+	}
+}
+
+func TestRouter_StaticInGroup(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/assets/files/test.txt", nil)
+	w := httptest.NewRecorder()
+
+	r := New()
+
+	r.Group("/assets").Static("/files", "./testdata")
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatal("response code is not 200, but ", w.Code)
+	}
+
+	if w.Body.String() != "hello world" {
+		t.Error("response body is not 'hello world'")
+	}
+}
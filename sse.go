@@ -0,0 +1,343 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is a single server-sent event. Multi-line data is split into one
+// data field per line.
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// EventStream writes server-sent events to a client. It is safe for
+// concurrent use.
+type EventStream struct {
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+	w      http.ResponseWriter
+	r      *http.Request
+	rc     *http.ResponseController
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+var ErrStreamClosed = errors.New("event stream closed")
+
+// SSE starts a server-sent event stream on w. The stream ends when the
+// client disconnects or Close is called.
+func SSE(w http.ResponseWriter, r *http.Request) (*EventStream, error) {
+
+	rc := http.NewResponseController(w)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	h.Del("Content-Length")
+
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+
+	return &EventStream{
+		w:      w,
+		r:      r,
+		rc:     rc,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// LastEventID returns the Last-Event-ID sent by a reconnecting client.
+func (s *EventStream) LastEventID() string {
+	return s.r.Header.Get("Last-Event-ID")
+}
+
+// Done is closed when the client disconnects or the stream is closed.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Close ends the stream and waits for the heartbeat to stop. Nothing is
+// written to the client once Close returns, so it must be called before the
+// handler returns.
+func (s *EventStream) Close() {
+
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *EventStream) Send(e Event) error {
+
+	var b strings.Builder
+
+	if e.ID != "" {
+		b.WriteString("id: " + stripNewlines(e.ID) + "\n")
+	}
+
+	if e.Event != "" {
+		b.WriteString("event: " + stripNewlines(e.Event) + "\n")
+	}
+
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(e.Data, "\r\n", "\n"), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// Comment sends a comment line, which clients ignore. Comments are useful as
+// heartbeats to keep idle connections open through proxies.
+func (s *EventStream) Comment(text string) error {
+	return s.write(": " + stripNewlines(text) + "\n\n")
+}
+
+// Heartbeat sends an empty comment every interval until the stream ends.
+func (s *EventStream) Heartbeat(interval time.Duration) {
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Comment(""); err != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+func (s *EventStream) write(data string) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || s.ctx.Err() != nil {
+		return ErrStreamClosed
+	}
+
+	if _, err := s.w.Write([]byte(data)); err != nil {
+		return err
+	}
+
+	return s.rc.Flush()
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// Hub fans out events published to a topic to every subscriber of that
+// topic. The last events of each topic are kept so that reconnecting clients
+// can resume from their Last-Event-ID.
+type Hub struct {
+	mu     sync.Mutex
+	replay int
+	nextID uint64
+	topics map[string]*hubTopic
+}
+
+type hubTopic struct {
+	events      []Event
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events of a topic on C. C is closed when the
+// subscription is cancelled, or when the subscriber falls too far behind.
+type Subscription struct {
+	C     <-chan Event
+	c     chan Event
+	hub   *Hub
+	topic string
+}
+
+const subscriptionBuffer = 64
+
+// NewHub returns a Hub that keeps up to replay events per topic.
+func NewHub(replay int) *Hub {
+	return &Hub{
+		replay: replay,
+		topics: map[string]*hubTopic{},
+	}
+}
+
+// Publish sends e to every subscriber of topic. Events without an ID are
+// given one from a sequence shared by all topics.
+func (h *Hub) Publish(topic string, e Event) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if e.ID == "" {
+		h.nextID++
+		e.ID = strconv.FormatUint(h.nextID, 10)
+	}
+
+	if _, ok := h.topics[topic]; !ok && h.replay == 0 {
+		// Nobody is subscribed and nothing is kept for later subscribers.
+		return
+	}
+
+	t := h.topic(topic)
+
+	if h.replay > 0 {
+		if len(t.events) >= h.replay {
+			t.events = append(t.events[:0], t.events[len(t.events)-h.replay+1:]...)
+		}
+		t.events = append(t.events, e)
+	}
+
+	for sub := range t.subscribers {
+		select {
+		case sub.c <- e:
+		default:
+			// The subscriber is not keeping up. Drop it so that it
+			// reconnects and catches up from the replay buffer.
+			delete(t.subscribers, sub)
+			close(sub.c)
+		}
+	}
+
+	h.release(topic, t)
+}
+
+// Subscribe subscribes to topic. Buffered events published after
+// lastEventID are delivered first; if lastEventID is no longer buffered the
+// whole buffer is replayed.
+func (h *Hub) Subscribe(topic, lastEventID string) *Subscription {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t := h.topic(topic)
+
+	var replay []Event
+
+	if lastEventID != "" {
+		replay = t.events
+		for i, e := range t.events {
+			if e.ID == lastEventID {
+				replay = t.events[i+1:]
+				break
+			}
+		}
+	}
+
+	c := make(chan Event, subscriptionBuffer+len(replay))
+	for _, e := range replay {
+		c <- e
+	}
+
+	sub := &Subscription{C: c, c: c, hub: h, topic: topic}
+	t.subscribers[sub] = struct{}{}
+
+	return sub
+}
+
+func (s *Subscription) Cancel() {
+
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	t, ok := s.hub.topics[s.topic]
+	if !ok {
+		return
+	}
+
+	if _, ok := t.subscribers[s]; ok {
+		delete(t.subscribers, s)
+		close(s.c)
+	}
+
+	s.hub.release(s.topic, t)
+}
+
+// Handler returns a handler that streams the events of topic to clients.
+func (h *Hub) Handler(topic string, heartbeat time.Duration) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		stream, err := SSE(w, r)
+		if err != nil {
+			return
+		}
+
+		defer stream.Close()
+
+		if heartbeat > 0 {
+			stream.Heartbeat(heartbeat)
+		}
+
+		sub := h.Subscribe(topic, stream.LastEventID())
+		defer sub.Cancel()
+
+		for {
+			select {
+			case <-stream.Done():
+				return
+			case e, ok := <-sub.C:
+				if !ok {
+					return
+				}
+
+				if err := stream.Send(e); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (h *Hub) topic(name string) *hubTopic {
+
+	t, ok := h.topics[name]
+	if !ok {
+		t = &hubTopic{subscribers: map[*Subscription]struct{}{}}
+		h.topics[name] = t
+	}
+
+	return t
+}
+
+// release removes topic once it has neither subscribers nor buffered events,
+// so that short-lived topics do not accumulate.
+func (h *Hub) release(name string, t *hubTopic) {
+
+	if len(t.subscribers) == 0 && len(t.events) == 0 {
+		delete(h.topics, name)
+	}
+}
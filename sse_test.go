@@ -0,0 +1,176 @@
+package router
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSE(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/events", nil)
+	req.Header.Set("Last-Event-ID", "41")
+	w := httptest.NewRecorder()
+
+	stream, err := SSE(w, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stream.LastEventID() != "41" {
+		t.Error("unexpected last event id: ", stream.LastEventID())
+	}
+
+	_ = stream.Send(Event{ID: "42", Event: "update", Data: "line 1\nline 2"})
+	_ = stream.Comment("ping")
+
+	if w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Error("unexpected content type: ", w.Header().Get("Content-Type"))
+	}
+
+	expected := "id: 42\nevent: update\ndata: line 1\ndata: line 2\n\n: ping\n\n"
+	if w.Body.String() != expected {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+
+	stream.Close()
+
+	if err := stream.Send(Event{Data: "late"}); err != ErrStreamClosed {
+		t.Error("expected ErrStreamClosed, got ", err)
+	}
+}
+
+func TestSSE_ClientDisconnect(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/events", nil)
+
+	stream, err := SSE(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	select {
+	case <-stream.Done():
+	case <-time.After(time.Second):
+		t.Fatal("stream did not end after client disconnect")
+	}
+}
+
+func TestSSE_HeartbeatStopsOnClose(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/events", nil)
+	w := httptest.NewRecorder()
+
+	stream, err := SSE(w, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream.Heartbeat(time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	stream.Close()
+
+	n := w.Body.Len()
+	time.Sleep(10 * time.Millisecond)
+
+	if w.Body.Len() != n {
+		t.Error("heartbeat wrote to the stream after Close returned")
+	}
+}
+
+func TestHub_Replay(t *testing.T) {
+
+	hub := NewHub(2)
+
+	hub.Publish("news", Event{Data: "a"})
+	hub.Publish("news", Event{Data: "b"})
+	hub.Publish("news", Event{Data: "c"})
+
+	sub := hub.Subscribe("news", "2")
+	defer sub.Cancel()
+
+	hub.Publish("news", Event{Data: "d"})
+	hub.Publish("other", Event{Data: "x"})
+
+	for _, expected := range []string{"c", "d"} {
+		e := <-sub.C
+		if e.Data != expected {
+			t.Errorf("expected event %s, got %s", expected, e.Data)
+		}
+	}
+
+	select {
+	case e := <-sub.C:
+		t.Error("unexpected event ", e)
+	default:
+	}
+}
+
+func TestHub_RemovesEmptyTopics(t *testing.T) {
+
+	hub := NewHub(0)
+
+	for i := 0; i < 100; i++ {
+		topic := "user-" + strconv.Itoa(i)
+		hub.Publish(topic, Event{Data: "ignored"})
+		hub.Subscribe(topic, "").Cancel()
+	}
+
+	sub := hub.Subscribe("news", "")
+
+	for i := 0; i < subscriptionBuffer+1; i++ {
+		hub.Publish("news", Event{Data: "x"})
+	}
+
+	if len(hub.topics) != 0 {
+		t.Error("expected no topics, got ", len(hub.topics))
+	}
+
+	sub.Cancel()
+}
+
+func TestHub_Handler(t *testing.T) {
+
+	hub := NewHub(10)
+
+	r := New()
+	r.Get("/events", hub.Handler("news", 0))
+
+	s := httptest.NewServer(r)
+	defer s.Close()
+
+	hub.Publish("news", Event{Data: "first"})
+
+	req, _ := http.NewRequest("GET", s.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", "0")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	hub.Publish("news", Event{Data: "second"})
+
+	var data []string
+
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() && len(data) < 2 {
+		if line := scanner.Text(); strings.HasPrefix(line, "data: ") {
+			data = append(data, strings.TrimPrefix(line, "data: "))
+		}
+	}
+
+	if strings.Join(data, ",") != "first,second" {
+		t.Error("unexpected events: ", data)
+	}
+}
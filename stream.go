@@ -0,0 +1,241 @@
+package router
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type StreamOption func(*StreamConfig)
+
+type StreamConfig struct {
+	FlushRecords   int
+	FlushInterval  time.Duration
+	EscapeFormulas bool
+}
+
+// WithFlushRecords flushes the response after every n records.
+func WithFlushRecords(n int) StreamOption {
+	return func(c *StreamConfig) {
+		c.FlushRecords = n
+	}
+}
+
+// WithFlushInterval flushes the response when d has passed since the last
+// flush. The interval is checked as records are written.
+func WithFlushInterval(d time.Duration) StreamOption {
+	return func(c *StreamConfig) {
+		c.FlushInterval = d
+	}
+}
+
+// WithFormulaEscaping prefixes CSV cells that spreadsheet applications would
+// evaluate as formulas with a single quote.
+func WithFormulaEscaping() StreamOption {
+	return func(c *StreamConfig) {
+		c.EscapeFormulas = true
+	}
+}
+
+// recordStream buffers records written to a response and flushes them to
+// the client periodically.
+type recordStream struct {
+	w           http.ResponseWriter
+	r           *http.Request
+	rc          *http.ResponseController
+	bw          *bufio.Writer
+	config      *StreamConfig
+	contentType string
+	started     bool
+	pending     int
+	lastFlush   time.Time
+}
+
+func newRecordStream(w http.ResponseWriter, r *http.Request, contentType string, opts []StreamOption) *recordStream {
+
+	config := &StreamConfig{
+		FlushRecords:  100,
+		FlushInterval: time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return &recordStream{
+		w:           w,
+		r:           r,
+		rc:          http.NewResponseController(w),
+		bw:          bufio.NewWriter(w),
+		config:      config,
+		contentType: contentType,
+	}
+}
+
+func (s *recordStream) begin() error {
+
+	if err := s.r.Context().Err(); err != nil {
+		return err
+	}
+
+	if !s.started {
+		s.started = true
+		s.lastFlush = time.Now()
+
+		s.w.Header().Set("Content-Type", s.contentType)
+		s.w.Header().Del("Content-Length")
+		s.w.WriteHeader(http.StatusOK)
+	}
+
+	return nil
+}
+
+func (s *recordStream) written() error {
+
+	s.pending++
+
+	if (s.config.FlushRecords > 0 && s.pending >= s.config.FlushRecords) ||
+		(s.config.FlushInterval > 0 && time.Since(s.lastFlush) >= s.config.FlushInterval) {
+		return s.flush()
+	}
+
+	return nil
+}
+
+func (s *recordStream) flush() error {
+
+	if err := s.bw.Flush(); err != nil {
+		return err
+	}
+
+	s.pending = 0
+	s.lastFlush = time.Now()
+
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
+
+// NDJSONWriter streams values as newline delimited JSON.
+type NDJSONWriter struct {
+	s   *recordStream
+	enc *json.Encoder
+}
+
+func NewNDJSONWriter(w http.ResponseWriter, r *http.Request, opts ...StreamOption) *NDJSONWriter {
+
+	s := newRecordStream(w, r, "application/x-ndjson", opts)
+
+	return &NDJSONWriter{
+		s:   s,
+		enc: json.NewEncoder(s.bw),
+	}
+}
+
+// Write writes v as a single line. It returns the request context's error
+// once the client has disconnected.
+func (n *NDJSONWriter) Write(v interface{}) error {
+
+	if err := n.s.begin(); err != nil {
+		return err
+	}
+
+	if err := n.enc.Encode(v); err != nil {
+		return err
+	}
+
+	return n.s.written()
+}
+
+// Flush sends buffered records to the client.
+func (n *NDJSONWriter) Flush() error {
+	return n.s.flush()
+}
+
+// Close flushes any buffered records. An empty stream still writes its
+// headers.
+func (n *NDJSONWriter) Close() error {
+
+	if err := n.s.begin(); err != nil {
+		return err
+	}
+
+	return n.s.flush()
+}
+
+// CSVWriter streams RFC 4180 CSV records.
+type CSVWriter struct {
+	s  *recordStream
+	cw *csv.Writer
+}
+
+func NewCSVWriter(w http.ResponseWriter, r *http.Request, opts ...StreamOption) *CSVWriter {
+
+	s := newRecordStream(w, r, "text/csv; charset=utf-8", opts)
+
+	cw := csv.NewWriter(s.bw)
+	cw.UseCRLF = true
+
+	return &CSVWriter{
+		s:  s,
+		cw: cw,
+	}
+}
+
+// Write writes a single record. It returns the request context's error once
+// the client has disconnected.
+func (c *CSVWriter) Write(record []string) error {
+
+	if err := c.s.begin(); err != nil {
+		return err
+	}
+
+	if c.s.config.EscapeFormulas {
+		escaped := make([]string, len(record))
+		for i, field := range record {
+			escaped[i] = escapeFormula(field)
+		}
+		record = escaped
+	}
+
+	if err := c.cw.Write(record); err != nil {
+		return err
+	}
+
+	// The csv.Writer buffers on its own, so push records through to the
+	// stream's buffer before deciding whether to flush.
+	c.cw.Flush()
+	if err := c.cw.Error(); err != nil {
+		return err
+	}
+
+	return c.s.written()
+}
+
+func (c *CSVWriter) Flush() error {
+	return c.s.flush()
+}
+
+func (c *CSVWriter) Close() error {
+
+	if err := c.s.begin(); err != nil {
+		return err
+	}
+
+	return c.s.flush()
+}
+
+func escapeFormula(field string) string {
+
+	if field != "" && strings.ContainsRune("=+-@\t\r", rune(field[0])) {
+		return "'" + field
+	}
+
+	return field
+}
@@ -0,0 +1,76 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNDJSONWriter(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/export", nil)
+	w := httptest.NewRecorder()
+
+	s := NewNDJSONWriter(w, req, WithFlushRecords(2))
+
+	_ = s.Write(map[string]int{"id": 1})
+
+	if w.Flushed {
+		t.Error("expected first record to be buffered")
+	}
+
+	_ = s.Write(map[string]int{"id": 2})
+
+	if !w.Flushed {
+		t.Error("expected records to be flushed after two writes")
+	}
+
+	_ = s.Write(map[string]int{"id": 3})
+	_ = s.Close()
+
+	if w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Error("unexpected content type: ", w.Header().Get("Content-Type"))
+	}
+
+	if w.Body.String() != "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/export", nil)
+	w := httptest.NewRecorder()
+
+	s := NewCSVWriter(w, req, WithFormulaEscaping())
+
+	_ = s.Write([]string{"name", "note"})
+	_ = s.Write([]string{"ada", "=HYPERLINK(\"http://evil\")"})
+	_ = s.Write([]string{"grace", "-1"})
+	_ = s.Close()
+
+	expected := "name,note\r\nada,\"'=HYPERLINK(\"\"http://evil\"\")\"\r\ngrace,'-1\r\n"
+	if w.Body.String() != expected {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestCSVWriter_ClientDisconnect(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/export", nil)
+	w := httptest.NewRecorder()
+
+	s := NewCSVWriter(w, req)
+
+	if err := s.Write([]string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	if err := s.Write([]string{"b"}); err != context.Canceled {
+		t.Error("expected context.Canceled, got ", err)
+	}
+}
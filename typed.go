@@ -0,0 +1,71 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+type typedHandler interface {
+	http.Handler
+	types() (in, out reflect.Type)
+}
+
+type typed[In, Out any] struct {
+	fn func(ctx context.Context, in In) (Out, error)
+}
+
+// Typed adapts fn to an http.Handler. The request is decoded into In with
+// Bind and checked with Validate, and the result is written with JSON.
+// Errors are rendered by the router's ErrorHandler.
+//
+// Registering the handler with Method records In and Out in the route table.
+//
+// Typed panics if the validate tags of In are invalid, so custom rules must
+// be registered with RegisterValidator before it is called.
+func Typed[In, Out any](fn func(ctx context.Context, in In) (Out, error)) http.Handler {
+
+	checkRules(reflect.TypeOf((*In)(nil)).Elem(), map[reflect.Type]bool{})
+
+	return &typed[In, Out]{fn: fn}
+}
+
+func (h *typed[In, Out]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	var in In
+
+	if err := Bind(r, &in); err != nil {
+
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) {
+			err = (&HTTPError{
+				Status:  http.StatusBadRequest,
+				Code:    "invalid_request",
+				Message: err.Error(),
+			}).Wrap(err)
+		}
+
+		Error(w, r, err)
+		return
+	}
+
+	if err := Validate(&in); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	out, err := h.fn(r.Context(), in)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if err := JSON(w, r, http.StatusOK, out); err != nil && r.Context().Err() == nil {
+		Error(w, r, err)
+	}
+}
+
+func (h *typed[In, Out]) types() (reflect.Type, reflect.Type) {
+	return reflect.TypeOf((*In)(nil)).Elem(), reflect.TypeOf((*Out)(nil)).Elem()
+}
@@ -0,0 +1,113 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type typedTestInput struct {
+	ID     int    `path:"id"`
+	Notify bool   `query:"notify"`
+	Name   string `json:"name"`
+}
+
+type typedTestOutput struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Notify bool   `json:"notify"`
+}
+
+func TestTyped(t *testing.T) {
+
+	req, _ := http.NewRequest("PUT", "/users/7?notify=true", strings.NewReader(`{"name":"ada"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r := New()
+
+	r.Method(http.MethodPut, "/users/:id", Typed(func(ctx context.Context, in typedTestInput) (typedTestOutput, error) {
+		return typedTestOutput{ID: in.ID, Name: in.Name, Notify: in.Notify}, nil
+	}))
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatal("response code is not 200, but ", w.Code)
+	}
+
+	if w.Body.String() != `{"id":7,"name":"ada","notify":true}` {
+		t.Error("unexpected response body: ", w.Body.String())
+	}
+
+	routes := r.GetRoutes()
+
+	if routes[0].Input != reflect.TypeOf(typedTestInput{}) {
+		t.Error("route input type is not typedTestInput, but ", routes[0].Input)
+	}
+
+	if routes[0].Output != reflect.TypeOf(typedTestOutput{}) {
+		t.Error("route output type is not typedTestOutput, but ", routes[0].Output)
+	}
+}
+
+func TestTyped_InvalidParam(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/users/abc", nil)
+	w := httptest.NewRecorder()
+
+	r := New()
+
+	r.Method(http.MethodGet, "/users/:id", Typed(func(ctx context.Context, in typedTestInput) (typedTestOutput, error) {
+		t.Fatal("handler should not be called")
+		return typedTestOutput{}, nil
+	}))
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatal("response code is not 400, but ", w.Code)
+	}
+}
+
+func TestTyped_Error(t *testing.T) {
+
+	req, _ := http.NewRequest("GET", "/users/1", nil)
+	w := httptest.NewRecorder()
+
+	r := New()
+
+	r.Method(http.MethodGet, "/users/:id", Typed(func(ctx context.Context, in typedTestInput) (*typedTestOutput, error) {
+		return nil, NewHTTPError(http.StatusNotFound, "user_not_found", "user does not exist")
+	}))
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatal("response code is not 404, but ", w.Code)
+	}
+}
+
+func TestTyped_InvalidTag(t *testing.T) {
+
+	type item struct {
+		SKU string `validate:"requried"`
+	}
+
+	type input struct {
+		Items []item `json:"items"`
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Typed to panic on an invalid validate tag")
+		}
+	}()
+
+	Typed(func(ctx context.Context, in input) (typedTestOutput, error) {
+		return typedTestOutput{}, nil
+	})
+}
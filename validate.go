@@ -0,0 +1,416 @@
+package router
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ValidatorFunc reports whether v satisfies a rule. param is the text after
+// '=' in the rule, or empty.
+type ValidatorFunc func(v reflect.Value, param string) bool
+
+// ValidationErrors is returned by Validate and rendered as 422 Unprocessable
+// Entity by DefaultErrorHandler.
+type ValidationErrors FieldErrors
+
+func (e ValidationErrors) Error() string {
+	return "validation failed: " + FieldErrors(e).Error()
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]ValidatorFunc{}
+	regexps      sync.Map
+	parsedTags   sync.Map
+)
+
+// RegisterValidator makes fn available as a rule in `validate` tags.
+// Registering a name twice replaces the earlier validator.
+func RegisterValidator(name string, fn ValidatorFunc) {
+
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+
+	validators[name] = fn
+}
+
+// Validate checks the struct pointed to by v against the rules in its
+// `validate` tags, for example `validate:"required,min=3,max=64"`.
+//
+// Built-in rules are required, omitempty, min, max, len, oneof, email and
+// regexp. min, max and len compare numbers by value and strings, slices and
+// maps by length. oneof takes a space separated list. regexp consumes the
+// rest of the tag so the expression may contain commas. dive applies the
+// rules that follow it to each element of a slice or map. Nested structs and
+// slices of structs are validated recursively.
+//
+// Field paths use the json name of each field, e.g. "items[0].name".
+func Validate(v interface{}) error {
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var vd validation
+	vd.validateStruct(rv, "")
+
+	if len(vd.errs) > 0 {
+		return vd.errs
+	}
+
+	return nil
+}
+
+type validation struct {
+	errs ValidationErrors
+}
+
+func (vd *validation) validateStruct(v reflect.Value, path string) {
+
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		fieldPath := path
+		if !f.Anonymous {
+			fieldPath = joinPath(path, fieldName(f))
+		}
+
+		vd.validateField(v.Field(i), fieldPath, parseRules(f.Tag.Get("validate")))
+	}
+}
+
+func (vd *validation) validateField(v reflect.Value, path string, rules []string) {
+
+	for i, rule := range rules {
+
+		name, param, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "omitempty":
+			if v.IsZero() {
+				return
+			}
+			continue
+
+		case "required":
+			if v.IsZero() {
+				vd.fail(path, "is required")
+				return
+			}
+			continue
+
+		case "dive":
+			v = indirect(v)
+
+			if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+				for j := 0; j < v.Len(); j++ {
+					vd.validateField(v.Index(j), fmt.Sprintf("%s[%d]", path, j), rules[i+1:])
+				}
+			} else if v.Kind() == reflect.Map {
+				iter := v.MapRange()
+				for iter.Next() {
+					vd.validateField(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), rules[i+1:])
+				}
+			}
+
+			return
+		}
+
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return
+		}
+
+		if !lookupValidator(name)(indirect(v), param) {
+			vd.fail(path, ruleMessage(name, param, indirect(v)))
+			return
+		}
+	}
+
+	vd.descend(v, path)
+}
+
+func (vd *validation) descend(v reflect.Value, path string) {
+
+	v = indirect(v)
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() != timeType {
+			vd.validateStruct(v, path)
+		}
+	case reflect.Slice, reflect.Array:
+		if k := indirectType(v.Type().Elem()).Kind(); k != reflect.Struct {
+			return
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			vd.descend(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func (vd *validation) fail(path, message string) {
+	vd.errs = append(vd.errs, &FieldError{
+		Field:   path,
+		Message: message,
+	})
+}
+
+func lookupValidator(name string) ValidatorFunc {
+
+	if fn, ok := builtinValidators[name]; ok {
+		return fn
+	}
+
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+
+	return validators[name]
+}
+
+var builtinValidators = map[string]ValidatorFunc{
+	"min": func(v reflect.Value, param string) bool {
+		n, ok := measure(v)
+		return ok && n >= parseParam(param)
+	},
+	"max": func(v reflect.Value, param string) bool {
+		n, ok := measure(v)
+		return ok && n <= parseParam(param)
+	},
+	"len": func(v reflect.Value, param string) bool {
+		n, ok := measure(v)
+		return ok && n == parseParam(param)
+	},
+	"oneof": func(v reflect.Value, param string) bool {
+		s := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(param) {
+			if s == option {
+				return true
+			}
+		}
+		return false
+	},
+	"email": func(v reflect.Value, param string) bool {
+		if v.Kind() != reflect.String {
+			return false
+		}
+		addr, err := mail.ParseAddress(v.String())
+		return err == nil && addr.Address == v.String()
+	},
+	"regexp": func(v reflect.Value, param string) bool {
+		if v.Kind() != reflect.String {
+			return false
+		}
+		re, _ := regexps.Load(param)
+		return re.(*regexp.Regexp).MatchString(v.String())
+	},
+}
+
+func ruleMessage(name, param string, v reflect.Value) string {
+
+	sized := v.Kind() == reflect.String || v.Kind() == reflect.Slice || v.Kind() == reflect.Array || v.Kind() == reflect.Map
+
+	switch name {
+	case "min":
+		if sized {
+			return "length must be at least " + param
+		}
+		return "must be at least " + param
+	case "max":
+		if sized {
+			return "length must be at most " + param
+		}
+		return "must be at most " + param
+	case "len":
+		if sized {
+			return "length must be " + param
+		}
+		return "must be " + param
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "email":
+		return "must be a valid email address"
+	case "regexp":
+		return "has an invalid format"
+	}
+
+	return "failed " + name + " validation"
+}
+
+// measure returns the value of numbers and the length of strings, slices
+// and maps.
+func measure(v reflect.Value) (float64, bool) {
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	}
+
+	return 0, false
+}
+
+func parseParam(param string) float64 {
+	n, _ := strconv.ParseFloat(param, 64)
+	return n
+}
+
+// parseRules splits a validate tag into rules and checks them once per tag.
+// Unknown rules, invalid numbers and invalid expressions are mistakes in the
+// program rather than in the request, so they panic instead of failing
+// validation.
+func parseRules(tag string) []string {
+
+	if rules, ok := parsedTags.Load(tag); ok {
+		return rules.([]string)
+	}
+
+	rules := splitRules(tag)
+
+	for _, rule := range rules {
+
+		name, param, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "omitempty", "required", "dive":
+			continue
+		case "min", "max", "len":
+			if _, err := strconv.ParseFloat(param, 64); err != nil {
+				panic(fmt.Sprintf("router: invalid %s parameter %q in validate tag %q", name, param, tag))
+			}
+		case "regexp":
+			re, err := regexp.Compile(param)
+			if err != nil {
+				panic(fmt.Sprintf("router: invalid regexp in validate tag %q: %v", tag, err))
+			}
+			regexps.LoadOrStore(param, re)
+		}
+
+		if lookupValidator(name) == nil {
+			panic(fmt.Sprintf("router: unknown validation rule %q in validate tag %q", name, tag))
+		}
+	}
+
+	parsedTags.Store(tag, rules)
+
+	return rules
+}
+
+// checkRules parses the validate tags of t and of the structs reachable
+// from it, so that invalid tags panic up front rather than on the first
+// request that is validated.
+func checkRules(t reflect.Type, seen map[reflect.Type]bool) {
+
+	t = indirectType(t)
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		checkRules(t.Elem(), seen)
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+
+	if t == timeType || seen[t] {
+		return
+	}
+
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		parseRules(f.Tag.Get("validate"))
+		checkRules(f.Type, seen)
+	}
+}
+
+func splitRules(tag string) []string {
+
+	if tag == "" {
+		return nil
+	}
+
+	var rules []string
+
+	for tag != "" {
+		if strings.HasPrefix(tag, "regexp=") {
+			rules = append(rules, tag)
+			break
+		}
+
+		rule, rest, _ := strings.Cut(tag, ",")
+		rules = append(rules, rule)
+		tag = rest
+	}
+
+	return rules
+}
+
+func fieldName(f reflect.StructField) string {
+
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+
+	return f.Name
+}
+
+func joinPath(path, name string) string {
+
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+func indirect(v reflect.Value) reflect.Value {
+
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	return v
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
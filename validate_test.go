@@ -0,0 +1,120 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type validateTestAddress struct {
+	Zip string `json:"zip" validate:"required,regexp=^[0-9]{5}$"`
+}
+
+type validateTestUser struct {
+	Name    string                `json:"name" validate:"required,min=2,max=8"`
+	Email   string                `json:"email" validate:"omitempty,email"`
+	Age     int                   `json:"age" validate:"min=18"`
+	Role    string                `json:"role" validate:"oneof=admin user"`
+	Tags    []string              `json:"tags" validate:"max=2,dive,len=3"`
+	Address *validateTestAddress  `json:"address"`
+	Others  []validateTestAddress `json:"others"`
+	Even    int                   `json:"even" validate:"even"`
+}
+
+func TestValidate(t *testing.T) {
+
+	RegisterValidator("even", func(v reflect.Value, param string) bool {
+		return v.Int()%2 == 0
+	})
+
+	user := validateTestUser{
+		Name:    "a",
+		Email:   "not-an-email",
+		Age:     17,
+		Role:    "root",
+		Tags:    []string{"abc", "toolong"},
+		Address: &validateTestAddress{Zip: "1234"},
+		Others:  []validateTestAddress{{Zip: "12345"}, {}},
+		Even:    3,
+	}
+
+	err := Validate(&user)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatal("expected ValidationErrors, got ", err)
+	}
+
+	expected := []string{"name", "email", "age", "role", "tags[1]", "address.zip", "others[1].zip", "even"}
+
+	if len(errs) != len(expected) {
+		t.Fatal("unexpected validation errors: ", errs)
+	}
+
+	for i, field := range expected {
+		if errs[i].Field != field {
+			t.Errorf("expected error %d for field %s, got %s", i, field, errs[i].Field)
+		}
+	}
+}
+
+func TestValidate_Valid(t *testing.T) {
+
+	user := validateTestUser{
+		Name: "ada",
+		Age:  36,
+		Role: "admin",
+	}
+
+	if err := Validate(&user); err != nil {
+		t.Error("expected no error, got ", err)
+	}
+}
+
+func TestValidate_Response(t *testing.T) {
+
+	req, _ := http.NewRequest("POST", "/", strings.NewReader(`{}`))
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+
+	Error(w, req, Validate(&validateTestAddress{}))
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatal("response code is not 422, but ", w.Code)
+	}
+
+	expected := `{"status":422,"code":"validation_failed","message":"request validation failed","errors":[{"field":"zip","message":"is required"}]}`
+	if w.Body.String() != expected {
+		t.Error("unexpected response body: ", w.Body.String())
+	}
+}
+
+func TestValidate_InvalidTag(t *testing.T) {
+
+	tests := map[string]interface{}{
+		"unknown rule": &struct {
+			Name string `validate:"requried"`
+		}{},
+		"invalid regexp": &struct {
+			Name string `validate:"regexp=[a-"`
+		}{},
+		"invalid number": &struct {
+			Name string `validate:"min=three"`
+		}{},
+	}
+
+	for name, v := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected Validate to panic", name)
+				}
+			}()
+
+			_ = Validate(v)
+		}()
+	}
+}
@@ -0,0 +1,46 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/ironfang-ltd/router-go/ws"
+)
+
+// WebSocketOption configures the upgrader of a WebSocket route.
+type WebSocketOption func(*ws.Upgrader)
+
+// WithCheckOrigin sets the function that decides whether the Origin of an
+// upgrade request is acceptable. By default it must match the host.
+func WithCheckOrigin(check func(r *http.Request) bool) WebSocketOption {
+	return func(u *ws.Upgrader) {
+		u.CheckOrigin = check
+	}
+}
+
+// WithSubprotocols sets the supported subprotocols in order of preference.
+func WithSubprotocols(protocols ...string) WebSocketOption {
+	return func(u *ws.Upgrader) {
+		u.Subprotocols = protocols
+	}
+}
+
+// WithMaxMessageSize limits the size of messages read from the client. A
+// negative size disables the limit.
+func WithMaxMessageSize(size int64) WebSocketOption {
+	return func(u *ws.Upgrader) {
+		u.MaxMessageSize = size
+	}
+}
+
+// WebSocket registers a GET route that upgrades to a WebSocket connection.
+// Middleware runs before the upgrade, so it can reject the request.
+func (r *router) WebSocket(path string, handler func(conn *ws.Conn), opts ...WebSocketOption) Route {
+
+	upgrader := &ws.Upgrader{}
+
+	for _, opt := range opts {
+		opt(upgrader)
+	}
+
+	return r.mapMethod(http.MethodGet, path, upgrader.Handler(handler))
+}
@@ -0,0 +1,430 @@
+package ws
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+	CloseMessage  MessageType = 8
+	PingMessage   MessageType = 9
+	PongMessage   MessageType = 10
+)
+
+const continuationFrame = 0
+
+// Close codes defined in RFC 6455, section 7.4.1.
+const (
+	CloseNormalClosure   = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseAbnormal        = 1006
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+const (
+	maxControlPayload     = 125
+	DefaultMaxMessageSize = 1 << 20 // 1 MiB
+)
+
+var (
+	ErrMessageTooBig = errors.New("ws: message exceeds size limit")
+	ErrCloseSent     = errors.New("ws: close frame already sent")
+)
+
+// CloseError is returned by ReadMessage when the peer closes the connection.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("ws: connection closed with code %d: %s", e.Code, e.Reason)
+}
+
+// Conn is a WebSocket connection. One goroutine may read while another
+// writes; writes are serialized internally.
+type Conn struct {
+	conn           net.Conn
+	br             *bufio.Reader
+	server         bool
+	subprotocol    string
+	maxMessageSize int64
+
+	writeMu   sync.Mutex
+	closeSent bool
+
+	pongHandler func(data []byte)
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, server bool, maxMessageSize int64) *Conn {
+
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+
+	if maxMessageSize == 0 {
+		maxMessageSize = DefaultMaxMessageSize
+	}
+
+	return &Conn{
+		conn:           conn,
+		br:             br,
+		server:         server,
+		maxMessageSize: maxMessageSize,
+	}
+}
+
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetMaxMessageSize limits the size of messages read from the peer. A
+// negative size disables the limit.
+func (c *Conn) SetMaxMessageSize(n int64) {
+	c.maxMessageSize = n
+}
+
+// SetPongHandler sets a function that is called from ReadMessage for every
+// pong frame received.
+func (c *Conn) SetPongHandler(h func(data []byte)) {
+	c.pongHandler = h
+}
+
+type frameHeader struct {
+	fin    bool
+	opcode int
+	masked bool
+	length int64
+	mask   [4]byte
+}
+
+// ReadMessage returns the next text or binary message. Fragmented messages
+// are reassembled, pings are answered and close frames are acknowledged
+// before a *CloseError is returned.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+
+	var (
+		messageType MessageType
+		message     []byte
+		inMessage   bool
+	)
+
+	for {
+		h, err := c.readHeader()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if h.opcode >= int(CloseMessage) {
+			payload, err := c.readPayload(h)
+			if err != nil {
+				return 0, nil, err
+			}
+
+			if err := c.handleControl(h.opcode, payload); err != nil {
+				return 0, nil, err
+			}
+
+			continue
+		}
+
+		switch {
+		case h.opcode == continuationFrame && !inMessage:
+			return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+		case h.opcode != continuationFrame && inMessage:
+			return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+		case h.opcode == int(TextMessage) || h.opcode == int(BinaryMessage):
+			messageType = MessageType(h.opcode)
+			inMessage = true
+		case h.opcode != continuationFrame:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+
+		if c.maxMessageSize >= 0 && int64(len(message))+h.length > c.maxMessageSize {
+			_ = c.fail(CloseMessageTooBig, "message too big")
+			return 0, nil, ErrMessageTooBig
+		}
+
+		payload, err := c.readPayload(h)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		message = append(message, payload...)
+
+		if h.fin {
+			if messageType == TextMessage && !utf8.Valid(message) {
+				return 0, nil, c.fail(CloseInvalidPayload, "invalid utf-8")
+			}
+
+			return messageType, message, nil
+		}
+	}
+}
+
+func (c *Conn) readHeader() (frameHeader, error) {
+
+	var h frameHeader
+	var b [8]byte
+
+	if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+		return h, err
+	}
+
+	h.fin = b[0]&0x80 != 0
+	h.opcode = int(b[0] & 0x0f)
+	h.masked = b[1]&0x80 != 0
+	h.length = int64(b[1] & 0x7f)
+
+	if b[0]&0x70 != 0 {
+		return h, c.fail(CloseProtocolError, "reserved bits set")
+	}
+
+	if h.masked != c.server {
+		return h, c.fail(CloseProtocolError, "invalid frame masking")
+	}
+
+	switch h.length {
+	case 126:
+		if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+			return h, err
+		}
+		h.length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, b[:8]); err != nil {
+			return h, err
+		}
+		n := binary.BigEndian.Uint64(b[:8])
+		if n>>63 != 0 {
+			return h, c.fail(CloseProtocolError, "invalid frame length")
+		}
+		h.length = int64(n)
+	}
+
+	if h.opcode >= int(CloseMessage) && (!h.fin || h.length > maxControlPayload) {
+		return h, c.fail(CloseProtocolError, "invalid control frame")
+	}
+
+	if h.masked {
+		if _, err := io.ReadFull(c.br, h.mask[:]); err != nil {
+			return h, err
+		}
+	}
+
+	return h, nil
+}
+
+func (c *Conn) readPayload(h frameHeader) ([]byte, error) {
+
+	payload := make([]byte, h.length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return nil, err
+	}
+
+	if h.masked {
+		maskBytes(h.mask, payload)
+	}
+
+	return payload, nil
+}
+
+func (c *Conn) handleControl(opcode int, payload []byte) error {
+
+	switch MessageType(opcode) {
+	case PingMessage:
+		err := c.writeFrame(PongMessage, payload)
+		if err != nil && !errors.Is(err, ErrCloseSent) {
+			return err
+		}
+	case PongMessage:
+		if c.pongHandler != nil {
+			c.pongHandler(payload)
+		}
+	case CloseMessage:
+		closeErr := &CloseError{Code: CloseNoStatus}
+
+		switch {
+		case len(payload) == 1:
+			return c.fail(CloseProtocolError, "invalid close payload")
+		case len(payload) >= 2:
+			closeErr.Code = int(binary.BigEndian.Uint16(payload))
+			closeErr.Reason = string(payload[2:])
+
+			if !validCloseCode(closeErr.Code) {
+				return c.fail(CloseProtocolError, "invalid close code")
+			}
+
+			if !utf8.ValidString(closeErr.Reason) {
+				return c.fail(CloseInvalidPayload, "invalid utf-8")
+			}
+		}
+
+		code := closeErr.Code
+		if code == CloseNoStatus {
+			code = CloseNormalClosure
+		}
+
+		_ = c.writeClose(code, "")
+
+		return closeErr
+	default:
+		return c.fail(CloseProtocolError, "unknown control opcode")
+	}
+
+	return nil
+}
+
+// fail sends a close frame with code and returns an error describing why.
+func (c *Conn) fail(code int, reason string) error {
+
+	_ = c.writeClose(code, reason)
+
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// WriteMessage sends data as a single, unfragmented frame.
+func (c *Conn) WriteMessage(messageType MessageType, data []byte) error {
+
+	if messageType >= CloseMessage {
+		if len(data) > maxControlPayload {
+			return errors.New("ws: control frame payload too large")
+		}
+
+		if messageType == CloseMessage {
+			return errors.New("ws: use Close to send close frames")
+		}
+	}
+
+	return c.writeFrame(messageType, data)
+}
+
+func (c *Conn) Ping(data []byte) error {
+	return c.WriteMessage(PingMessage, data)
+}
+
+// Close sends a normal closure frame and closes the underlying connection.
+func (c *Conn) Close() error {
+	return c.CloseWithReason(CloseNormalClosure, "")
+}
+
+func (c *Conn) CloseWithReason(code int, reason string) error {
+
+	_ = c.writeClose(code, reason)
+
+	return c.conn.Close()
+}
+
+func (c *Conn) writeClose(code int, reason string) error {
+
+	payload := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	copy(payload[2:], reason)
+
+	if len(payload) > maxControlPayload {
+		payload = payload[:maxControlPayload]
+	}
+
+	return c.writeFrame(CloseMessage, payload)
+}
+
+func (c *Conn) writeFrame(messageType MessageType, data []byte) error {
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrCloseSent
+	}
+
+	if messageType == CloseMessage {
+		c.closeSent = true
+	}
+
+	frame := make([]byte, 0, 14+len(data))
+	frame = append(frame, 0x80|byte(messageType))
+
+	var maskBit byte
+	if !c.server {
+		maskBit = 0x80
+	}
+
+	switch n := len(data); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	if c.server {
+		frame = append(frame, data...)
+	} else {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, data...)
+		maskBytes(mask, frame[start:])
+	}
+
+	_, err := c.conn.Write(frame)
+
+	return err
+}
+
+// validCloseCode reports whether code may be sent in a close frame.
+func validCloseCode(code int) bool {
+
+	switch code {
+	case CloseNoStatus, CloseAbnormal, 1015:
+		return false
+	}
+
+	return (code >= 1000 && code <= 1014) || (code >= 3000 && code <= 4999)
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i&3]
+	}
+}
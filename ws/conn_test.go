@@ -0,0 +1,221 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, u *Upgrader, fn func(conn *Conn)) (*httptest.Server, string) {
+
+	s := httptest.NewServer(u.Handler(fn))
+	t.Cleanup(s.Close)
+
+	return s, "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+func dial(t *testing.T, url string) *Conn {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, _, err := Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func echo(conn *Conn) {
+	for {
+		mt, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if err := conn.WriteMessage(mt, data); err != nil {
+			return
+		}
+	}
+}
+
+func TestConn_Echo(t *testing.T) {
+
+	_, url := newTestServer(t, &Upgrader{}, echo)
+
+	conn := dial(t, url)
+
+	large := bytes.Repeat([]byte("x"), 70000)
+
+	tests := []struct {
+		mt   MessageType
+		data []byte
+	}{
+		{TextMessage, []byte("hello")},
+		{BinaryMessage, []byte{0, 1, 2}},
+		{BinaryMessage, large},
+	}
+
+	for _, tt := range tests {
+
+		if err := conn.WriteMessage(tt.mt, tt.data); err != nil {
+			t.Fatal(err)
+		}
+
+		mt, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if mt != tt.mt || !bytes.Equal(data, tt.data) {
+			t.Errorf("unexpected echo of %d byte message", len(tt.data))
+		}
+	}
+}
+
+func TestConn_Fragmented(t *testing.T) {
+
+	_, url := newTestServer(t, &Upgrader{}, echo)
+
+	conn := dial(t, url)
+
+	// Write a text message in two fragments with a ping in between.
+	frames := [][]byte{
+		clientFrame(0x01, []byte("hel")),
+		clientFrame(0x89, []byte("ping")),
+		clientFrame(0x80, []byte("lo")),
+	}
+
+	for _, f := range frames {
+		if _, err := conn.conn.Write(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var pong []byte
+	conn.SetPongHandler(func(data []byte) {
+		pong = data
+	})
+
+	mt, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mt != TextMessage || string(data) != "hello" {
+		t.Errorf("unexpected message %q", data)
+	}
+
+	if string(pong) != "ping" {
+		t.Errorf("expected pong before message, got %q", pong)
+	}
+}
+
+func TestConn_MaxMessageSize(t *testing.T) {
+
+	done := make(chan error, 1)
+
+	_, url := newTestServer(t, &Upgrader{MaxMessageSize: 8}, func(conn *Conn) {
+		_, _, err := conn.ReadMessage()
+		done <- err
+	})
+
+	conn := dial(t, url)
+
+	_ = conn.WriteMessage(TextMessage, []byte("this is too long"))
+
+	if err := <-done; !errors.Is(err, ErrMessageTooBig) {
+		t.Fatal("expected ErrMessageTooBig, got ", err)
+	}
+
+	_, _, err := conn.ReadMessage()
+
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseMessageTooBig {
+		t.Fatal("expected close code 1009, got ", err)
+	}
+}
+
+func TestConn_Close(t *testing.T) {
+
+	done := make(chan error, 1)
+
+	_, url := newTestServer(t, &Upgrader{}, func(conn *Conn) {
+		_, _, err := conn.ReadMessage()
+		done <- err
+	})
+
+	conn := dial(t, url)
+
+	_ = conn.CloseWithReason(CloseGoingAway, "bye")
+
+	err := <-done
+
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseGoingAway || closeErr.Reason != "bye" {
+		t.Fatal("expected close error 1001, got ", err)
+	}
+}
+
+func TestUpgrade_Origin(t *testing.T) {
+
+	s, url := newTestServer(t, &Upgrader{}, echo)
+
+	header := http.Header{"Origin": {"https://evil.example"}}
+
+	_, res, err := Dial(context.Background(), url, header)
+	if err == nil {
+		t.Fatal("expected handshake to fail")
+	}
+
+	if res == nil || res.StatusCode != http.StatusForbidden {
+		t.Fatal("expected 403 response")
+	}
+
+	header = http.Header{"Origin": {s.URL}}
+
+	conn, _, err := Dial(context.Background(), url, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Close()
+}
+
+func TestUpgrade_NotWebSocket(t *testing.T) {
+
+	s, _ := newTestServer(t, &Upgrader{}, echo)
+
+	res, err := http.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatal("expected 400, got ", res.StatusCode)
+	}
+}
+
+// clientFrame builds a masked frame with the given first header byte.
+func clientFrame(b0 byte, payload []byte) []byte {
+
+	mask := [4]byte{1, 2, 3, 4}
+
+	frame := []byte{b0, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+
+	masked := append([]byte(nil), payload...)
+	maskBytes(mask, masked)
+
+	return append(frame, masked...)
+}
@@ -0,0 +1,296 @@
+package ws
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// HandshakeError is returned by Upgrade when the request is not a valid
+// WebSocket handshake. The HTTP response has already been written.
+type HandshakeError struct {
+	Status  int
+	Message string
+}
+
+func (e *HandshakeError) Error() string {
+	return "ws: " + e.Message
+}
+
+type Upgrader struct {
+	// CheckOrigin returns true if the request Origin is acceptable. By
+	// default requests with an Origin must come from the same host.
+	CheckOrigin func(r *http.Request) bool
+
+	// Subprotocols are the supported subprotocols in order of preference.
+	Subprotocols []string
+
+	// MaxMessageSize limits the size of messages read from the client.
+	// Zero uses DefaultMaxMessageSize, a negative value disables the limit.
+	MaxMessageSize int64
+}
+
+// Upgrade performs the RFC 6455 opening handshake and hijacks the
+// connection. header is added to the 101 response.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, header http.Header) (*Conn, error) {
+
+	if r.Method != http.MethodGet {
+		return nil, u.reject(w, http.StatusMethodNotAllowed, "method must be GET")
+	}
+
+	if !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, u.reject(w, http.StatusBadRequest, "not a websocket handshake")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, u.reject(w, http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if k, err := base64.StdEncoding.DecodeString(key); err != nil || len(k) != 16 {
+		return nil, u.reject(w, http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+
+	if !checkOrigin(r) {
+		return nil, u.reject(w, http.StatusForbidden, "origin not allowed")
+	}
+
+	subprotocol := u.selectSubprotocol(r)
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, u.reject(w, http.StatusInternalServerError, "connection does not support hijacking")
+	}
+
+	var b strings.Builder
+
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	b.WriteString("Upgrade: websocket\r\n")
+	b.WriteString("Connection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+
+	for k, values := range header {
+		for _, v := range values {
+			b.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+
+	b.WriteString("\r\n")
+
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	c := newConn(netConn, brw.Reader, true, u.MaxMessageSize)
+	c.subprotocol = subprotocol
+
+	return c, nil
+}
+
+// Handler returns a handler that upgrades the request and calls fn with the
+// connection. The connection is closed when fn returns.
+func (u *Upgrader) Handler(fn func(conn *Conn)) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		conn, err := u.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		fn(conn)
+	}
+}
+
+func (u *Upgrader) reject(w http.ResponseWriter, status int, message string) error {
+
+	http.Error(w, http.StatusText(status), status)
+
+	return &HandshakeError{Status: status, Message: message}
+}
+
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+
+	requested := headerTokens(r.Header, "Sec-WebSocket-Protocol")
+
+	for _, supported := range u.Subprotocols {
+		for _, p := range requested {
+			if p == supported {
+				return p
+			}
+		}
+	}
+
+	return ""
+}
+
+func sameOrigin(r *http.Request) bool {
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+func headerTokens(h http.Header, name string) []string {
+
+	var tokens []string
+
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+
+	return tokens
+}
+
+func headerContainsToken(h http.Header, name, token string) bool {
+
+	for _, t := range headerTokens(h, name) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Dial opens a client connection to a ws:// or wss:// URL.
+func Dial(ctx context.Context, rawURL string, header http.Header) (*Conn, *http.Response, error) {
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	host := u.Host
+	useTLS := false
+
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+	case "wss":
+		u.Scheme = "https"
+		useTLS = true
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+	default:
+		return nil, nil, fmt.Errorf("ws: unsupported scheme %q", u.Scheme)
+	}
+
+	var d net.Dialer
+
+	netConn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if useTLS {
+		tlsConn := tls.Client(netConn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			netConn.Close()
+			return nil, nil, err
+		}
+		netConn = tlsConn
+	}
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Host:       u.Host,
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = netConn.SetDeadline(deadline)
+	}
+
+	if err := req.Write(netConn); err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(netConn)
+
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+
+	if res.StatusCode != http.StatusSwitchingProtocols || res.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		netConn.Close()
+		return nil, res, errors.New("ws: bad handshake")
+	}
+
+	_ = netConn.SetDeadline(time.Time{})
+
+	c := newConn(netConn, br, false, 0)
+	c.subprotocol = res.Header.Get("Sec-WebSocket-Protocol")
+
+	return c, res, nil
+}